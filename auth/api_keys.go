@@ -0,0 +1,67 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// APIKey is a static key accepted in the x-api-key metadata. Only the SHA-256
+// of the key is kept in the keys file.
+type APIKey struct {
+	Name    string   `json:"name"`
+	KeyHash string   `json:"key_sha256"`
+	Roles   []string `json:"roles"`
+}
+
+type apiKeysFile struct {
+	Keys []APIKey `json:"keys"`
+}
+
+// LoadAPIKeys reads the API keys file, a JSON document of the form
+// {"keys": [{"name": "ci", "key_sha256": "<hex>", "roles": ["admin"]}]}.
+func LoadAPIKeys(path string) ([]APIKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file apiKeysFile
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return nil, fmt.Errorf("invalid API keys file %s: %w", path, err)
+	}
+
+	for i := range file.Keys {
+		key := &file.Keys[i]
+		if key.Name == "" {
+			return nil, fmt.Errorf("invalid API keys file %s: key without a name", path)
+		}
+		hash, err := hex.DecodeString(key.KeyHash)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid API keys file %s: key %s has no valid key_sha256", path, key.Name)
+		}
+		key.KeyHash = hex.EncodeToString(hash)
+	}
+
+	return file.Keys, nil
+}
+
+// findAPIKey returns the API key matching the presented key, or nil. Every key
+// is compared, in constant time, so the timing does not tell which one is
+// closest.
+func findAPIKey(keys []APIKey, presentedKey string) *APIKey {
+	sum := sha256.Sum256([]byte(presentedKey))
+	presentedHash := hex.EncodeToString(sum[:])
+
+	var match *APIKey
+	for i := range keys {
+		if subtle.ConstantTimeCompare([]byte(presentedHash), []byte(keys[i].KeyHash)) == 1 {
+			match = &keys[i]
+		}
+	}
+	return match
+}
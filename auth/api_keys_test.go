@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestFindAPIKey(t *testing.T) {
+	keys := []APIKey{
+		{Name: "ci", KeyHash: hashKey("ci-secret"), Roles: []string{"admin"}},
+		{Name: "reader", KeyHash: hashKey("reader-secret"), Roles: []string{"reader"}},
+	}
+
+	tests := []struct {
+		name         string
+		presentedKey string
+		want         string
+	}{
+		{name: "first key", presentedKey: "ci-secret", want: "ci"},
+		{name: "second key", presentedKey: "reader-secret", want: "reader"},
+		{name: "unknown key", presentedKey: "other-secret"},
+		{name: "hash instead of key", presentedKey: hashKey("ci-secret")},
+		{name: "empty key", presentedKey: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := findAPIKey(keys, test.presentedKey)
+			var got string
+			if key != nil {
+				got = key.Name
+			}
+			if got != test.want {
+				t.Errorf("findAPIKey(%q) = %q, want %q", test.presentedKey, got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadAPIKeys(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// the normalized hash of the first key
+		wantHash string
+		wantErr  bool
+	}{
+		{
+			name:     "valid file",
+			content:  `{"keys": [{"name": "ci", "key_sha256": "` + hashKey("ci-secret") + `", "roles": ["admin"]}]}`,
+			wantHash: hashKey("ci-secret"),
+		},
+		{
+			name:     "upper case hash",
+			content:  `{"keys": [{"name": "ci", "key_sha256": "` + strings.ToUpper(hashKey("ci-secret")) + `"}]}`,
+			wantHash: hashKey("ci-secret"),
+		},
+		{
+			name:    "missing name",
+			content: `{"keys": [{"key_sha256": "` + hashKey("ci-secret") + `"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "short hash",
+			content: `{"keys": [{"name": "ci", "key_sha256": "abcd"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			content: `{"keys": [`,
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "api_keys.json")
+			err := os.WriteFile(path, []byte(test.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			keys, err := LoadAPIKeys(path)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("LoadAPIKeys() = %v, want an error", keys)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadAPIKeys() error = %v", err)
+			}
+			if keys[0].KeyHash != test.wantHash {
+				t.Errorf("LoadAPIKeys() hash = %q, want %q", keys[0].KeyHash, test.wantHash)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// the metadata keys carrying the credentials
+const (
+	APIKeyMetadataKey        = "x-api-key"
+	AuthorizationMetadataKey = "authorization"
+)
+
+const bearerPrefix = "Bearer "
+
+// the services callable without credentials, so orchestrators can probe the
+// server
+var publicServicePrefixes = []string{"/grpc.health.v1.Health/"}
+
+// IsPublicMethod reports whether the method is exempt from authentication and
+// authorization.
+func IsPublicMethod(fullMethod string) bool {
+	for _, prefix := range publicServicePrefixes {
+		if strings.HasPrefix(fullMethod, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config locates the credentials the server accepts. At least one of the
+// files must be set.
+type Config struct {
+	// the JSON file listing the API keys, see LoadAPIKeys
+	APIKeysFile string
+	// the JWKS file holding the HMAC keys JWTs are signed with
+	JWKSFile string
+	// the iss and aud claims JWTs must carry, not checked when empty
+	Issuer   string
+	Audience string
+}
+
+// Authenticator checks the credentials in the request metadata and attaches
+// the resulting principal to the request context.
+type Authenticator struct {
+	apiKeys  []APIKey
+	jwtKeys  []JWTKey
+	issuer   string
+	audience string
+}
+
+func NewAuthenticator(config Config) (*Authenticator, error) {
+	if config.APIKeysFile == "" && config.JWKSFile == "" {
+		return nil, errors.New("no API keys file nor JWKS file configured")
+	}
+
+	authenticator := &Authenticator{issuer: config.Issuer, audience: config.Audience}
+
+	if config.APIKeysFile != "" {
+		apiKeys, err := LoadAPIKeys(config.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.apiKeys = apiKeys
+	}
+
+	if config.JWKSFile != "" {
+		jwtKeys, err := LoadJWKS(config.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		authenticator.jwtKeys = jwtKeys
+	}
+
+	return authenticator, nil
+}
+
+// Authenticate returns the principal of the credentials in the request
+// metadata, or an Unauthenticated error. An API key takes precedence over a
+// bearer token.
+func (a *Authenticator) Authenticate(ctx context.Context) (*Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if apiKeys := md.Get(APIKeyMetadataKey); len(apiKeys) > 0 {
+		apiKey := findAPIKey(a.apiKeys, apiKeys[0])
+		if apiKey == nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid API key")
+		}
+		return &Principal{Subject: apiKey.Name, Method: MethodAPIKey, Roles: apiKey.Roles}, nil
+	}
+
+	if authorizations := md.Get(AuthorizationMetadataKey); len(authorizations) > 0 {
+		token, ok := strings.CutPrefix(authorizations[0], bearerPrefix)
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+		}
+		claims, err := verifyJWT(token, a.jwtKeys, a.issuer, a.audience, time.Now())
+		if err != nil {
+			log.Printf("rejected JWT: %v", err)
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return &Principal{Subject: claims.Subject, Method: MethodJWT, Roles: claims.Roles}, nil
+	}
+
+	return nil, status.Error(codes.Unauthenticated, "missing credentials")
+}
+
+// UnaryServerInterceptor rejects the unauthenticated unary calls.
+func (a *Authenticator) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if IsPublicMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		principal, err := a.Authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(WithPrincipal(ctx, principal), req)
+	}
+}
+
+// StreamServerInterceptor rejects the unauthenticated streaming calls.
+func (a *Authenticator) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if IsPublicMethod(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		principal, err := a.Authenticate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: WithPrincipal(ss.Context(), principal)})
+	}
+}
+
+// authenticatedStream overrides the context of a stream with one carrying
+// the principal.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strings"
+	"time"
+)
+
+// the HMAC algorithms JWTs may be signed with
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// how far the exp and nbf claims may be off because of clock skew
+const jwtLeeway = 30 * time.Second
+
+// JWTKey is a symmetric key JWTs are verified against.
+type JWTKey struct {
+	Id string
+	// the algorithm the key is restricted to, empty for any HMAC one
+	Algorithm string
+	Secret    []byte
+}
+
+// jsonWebKey is an entry of a JWKS file, only octet keys are supported.
+type jsonWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyId     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Key       string `json:"k"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// LoadJWKS reads the HMAC keys of a local JWKS file.
+func LoadJWKS(path string) ([]JWTKey, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keySet jsonWebKeySet
+	err = json.Unmarshal(content, &keySet)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWKS file %s: %w", path, err)
+	}
+
+	keys := make([]JWTKey, 0, len(keySet.Keys))
+	for _, key := range keySet.Keys {
+		if key.KeyType != "oct" {
+			return nil, fmt.Errorf("invalid JWKS file %s: key %q is not an octet key", path, key.KeyId)
+		}
+		if _, ok := jwtAlgorithms[key.Algorithm]; key.Algorithm != "" && !ok {
+			return nil, fmt.Errorf("invalid JWKS file %s: key %q has unsupported algorithm %s", path, key.KeyId, key.Algorithm)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(key.Key, "="))
+		if err != nil || len(secret) == 0 {
+			return nil, fmt.Errorf("invalid JWKS file %s: key %q has no valid k", path, key.KeyId)
+		}
+		keys = append(keys, JWTKey{Id: key.KeyId, Algorithm: key.Algorithm, Secret: secret})
+	}
+
+	return keys, nil
+}
+
+type jwtHeader struct {
+	Algorithm string `json:"alg"`
+	KeyId     string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	Roles     []string        `json:"roles"`
+}
+
+// hasAudience reports whether the aud claim, a string or a list of strings,
+// contains the audience.
+func (c *jwtClaims) hasAudience(audience string) bool {
+	var single string
+	if json.Unmarshal(c.Audience, &single) == nil {
+		return single == audience
+	}
+	var list []string
+	if json.Unmarshal(c.Audience, &list) == nil {
+		for _, a := range list {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// verifyJWT checks the signature and the registered claims of the token and
+// returns its claims. The exp and sub claims are required, iss and aud are
+// only checked when an issuer or audience is configured.
+func verifyJWT(token string, keys []JWTKey, issuer, audience string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header jwtHeader
+	err := decodeJWTSegment(parts[0], &header)
+	if err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	newHash, ok := jwtAlgorithms[header.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm %q", header.Algorithm)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+
+	// the kid picks the key, without one every key is tried
+	verified := false
+	for _, key := range keys {
+		if header.KeyId != "" && key.Id != header.KeyId {
+			continue
+		}
+		if key.Algorithm != "" && key.Algorithm != header.Algorithm {
+			continue
+		}
+		mac := hmac.New(newHash, key.Secret)
+		mac.Write([]byte(parts[0] + "." + parts[1]))
+		if hmac.Equal(mac.Sum(nil), signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, errors.New("invalid signature")
+	}
+
+	var claims jwtClaims
+	err = decodeJWTSegment(parts[1], &claims)
+	if err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+
+	if claims.ExpiresAt == nil {
+		return nil, errors.New("missing exp claim")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return nil, errors.New("token not valid yet")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("missing sub claim")
+	}
+	if issuer != "" && claims.Issuer != issuer {
+		return nil, fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if audience != "" && !claims.hasAudience(audience) {
+		return nil, errors.New("unexpected audience")
+	}
+
+	return &claims, nil
+}
+
+func decodeJWTSegment(segment string, v any) error {
+	content, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(content, v)
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// signJWT returns a token of the header and claims signed with the secret.
+func signJWT(t *testing.T, header, claims map[string]any, secret []byte) string {
+	t.Helper()
+
+	encode := func(v any) string {
+		content, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(content)
+	}
+
+	signingInput := encode(header) + "." + encode(claims)
+	mac := hmac.New(jwtAlgorithms[header["alg"].(string)], secret)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyJWT(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret := []byte("primary-secret")
+	keys := []JWTKey{
+		{Id: "primary", Secret: secret},
+		{Id: "restricted", Algorithm: "HS512", Secret: []byte("restricted-secret")},
+	}
+
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub":   "alice",
+			"iss":   "issuer",
+			"aud":   "schema-service",
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{"admin"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+	hs256 := map[string]any{"alg": "HS256", "kid": "primary"}
+
+	tests := []struct {
+		name     string
+		token    string
+		issuer   string
+		audience string
+		wantErr  bool
+	}{
+		{
+			name:     "valid token",
+			token:    signJWT(t, hs256, claims(nil), secret),
+			issuer:   "issuer",
+			audience: "schema-service",
+		},
+		{
+			name:  "without kid every key is tried",
+			token: signJWT(t, map[string]any{"alg": "HS512"}, claims(nil), []byte("restricted-secret")),
+		},
+		{
+			name:     "audience list",
+			token:    signJWT(t, hs256, claims(map[string]any{"aud": []string{"other", "schema-service"}}), secret),
+			audience: "schema-service",
+		},
+		{
+			name:  "expired within the leeway",
+			token: signJWT(t, hs256, claims(map[string]any{"exp": now.Add(-10 * time.Second).Unix()}), secret),
+		},
+		{
+			name:    "expired",
+			token:   signJWT(t, hs256, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()}), secret),
+			wantErr: true,
+		},
+		{
+			name:    "missing exp",
+			token:   signJWT(t, hs256, claims(map[string]any{"exp": nil}), secret),
+			wantErr: true,
+		},
+		{
+			name:    "not valid yet",
+			token:   signJWT(t, hs256, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()}), secret),
+			wantErr: true,
+		},
+		{
+			name:    "missing sub",
+			token:   signJWT(t, hs256, claims(map[string]any{"sub": nil}), secret),
+			wantErr: true,
+		},
+		{
+			name:    "wrong secret",
+			token:   signJWT(t, hs256, claims(nil), []byte("other-secret")),
+			wantErr: true,
+		},
+		{
+			name:    "unknown kid",
+			token:   signJWT(t, map[string]any{"alg": "HS256", "kid": "unknown"}, claims(nil), secret),
+			wantErr: true,
+		},
+		{
+			name:    "algorithm the key is restricted from",
+			token:   signJWT(t, map[string]any{"alg": "HS256", "kid": "restricted"}, claims(nil), []byte("restricted-secret")),
+			wantErr: true,
+		},
+		{
+			name:    "unsigned token",
+			token:   base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.",
+			wantErr: true,
+		},
+		{
+			name:    "unexpected issuer",
+			token:   signJWT(t, hs256, claims(nil), secret),
+			issuer:  "other",
+			wantErr: true,
+		},
+		{
+			name:     "unexpected audience",
+			token:    signJWT(t, hs256, claims(nil), secret),
+			audience: "other",
+			wantErr:  true,
+		},
+		{
+			name:    "malformed token",
+			token:   "not-a-token",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := verifyJWT(test.token, keys, test.issuer, test.audience, now)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("verifyJWT() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verifyJWT() error = %v", err)
+			}
+			if got.Subject != "alice" {
+				t.Errorf("verifyJWT() subject = %q, want %q", got.Subject, "alice")
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Policy grants RPCs to roles and restricts tables to some principals. Calls
+// not granted by any role of the principal are denied.
+//
+// Method patterns are matched with path.Match, against the full method name
+// when they contain a slash and against the bare RPC name otherwise, e.g.
+// "List*" or "/schema_management_service.SchemaService/DropTable". Project
+// patterns are matched against the project ids, the default database being
+// the project "", which only "*" matches.
+type Policy struct {
+	// the RPCs each role may call
+	Roles map[string]RolePolicy `json:"roles"`
+	// the tables only some principals may touch, keyed by table name pattern
+	Tables map[string]TablePolicy `json:"tables"`
+}
+
+type RolePolicy struct {
+	Methods []string `json:"methods"`
+	// the projects the role applies in, every project when empty
+	Projects []string `json:"projects"`
+}
+
+// TablePolicy lists who may call RPCs touching the table, by subject or role.
+type TablePolicy struct {
+	Subjects []string `json:"subjects"`
+	Roles    []string `json:"roles"`
+	// the projects the restriction applies in, every project when empty
+	Projects []string `json:"projects"`
+}
+
+// LoadPolicy reads and checks a policy file.
+func LoadPolicy(policyPath string) (*Policy, error) {
+	content, err := os.ReadFile(policyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	err = json.Unmarshal(content, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", policyPath, err)
+	}
+
+	// reject malformed patterns now rather than denying every call later
+	for role, rolePolicy := range policy.Roles {
+		for _, pattern := range rolePolicy.Methods {
+			_, err = path.Match(pattern, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy file %s: role %s has invalid method pattern %q", policyPath, role, pattern)
+			}
+		}
+		for _, pattern := range rolePolicy.Projects {
+			_, err = path.Match(pattern, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy file %s: role %s has invalid project pattern %q", policyPath, role, pattern)
+			}
+		}
+	}
+	for pattern, tablePolicy := range policy.Tables {
+		_, err = path.Match(pattern, "")
+		if err != nil {
+			return nil, fmt.Errorf("invalid policy file %s: invalid table pattern %q", policyPath, pattern)
+		}
+		for _, projectPattern := range tablePolicy.Projects {
+			_, err = path.Match(projectPattern, "")
+			if err != nil {
+				return nil, fmt.Errorf("invalid policy file %s: table %s has invalid project pattern %q", policyPath, pattern, projectPattern)
+			}
+		}
+	}
+
+	return &policy, nil
+}
+
+// allowsMethod reports whether any role of the principal grants the method in
+// the project.
+func (p *Policy) allowsMethod(principal *Principal, fullMethod, projectId string) bool {
+	methodName := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, role := range principal.Roles {
+		rolePolicy := p.Roles[role]
+		if !matchesProject(rolePolicy.Projects, projectId) {
+			continue
+		}
+		for _, pattern := range rolePolicy.Methods {
+			name := methodName
+			if strings.Contains(pattern, "/") {
+				name = fullMethod
+			}
+			if matched, _ := path.Match(pattern, name); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowsTable reports whether the principal may touch the table of the
+// project. Tables no pattern matches are open to everyone the RPC is granted
+// to.
+func (p *Policy) allowsTable(principal *Principal, projectId, tableName string) bool {
+	for pattern, tablePolicy := range p.Tables {
+		if matched, _ := path.Match(pattern, tableName); !matched {
+			continue
+		}
+		if !matchesProject(tablePolicy.Projects, projectId) {
+			continue
+		}
+		if !contains(tablePolicy.Subjects, principal.Subject) && !containsAny(tablePolicy.Roles, principal.Roles) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesProject reports whether any pattern matches the project, or there is
+// no pattern.
+func matchesProject(patterns []string, projectId string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, projectId); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(values, candidates []string) bool {
+	for _, candidate := range candidates {
+		if contains(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resource is a project a call touches, and the table of it when the call
+// touches one. The default database is the project "".
+type Resource struct {
+	ProjectId string
+	TableName string
+}
+
+// ResourceResolver returns every project and table the call touches, looking
+// up those the request does not name, e.g. the table of a trash entry. The
+// returned context is the one the call proceeds with, carrying what was looked
+// up so the handler acts on what was authorized. It returns ErrUnknownRequest
+// for the requests it does not cover.
+type ResourceResolver func(ctx context.Context, fullMethod string, req any) (context.Context, []Resource, error)
+
+// ErrUnknownRequest is returned by resolvers for the requests they do not
+// cover, which are denied.
+var ErrUnknownRequest = errors.New("unknown request")
+
+// Authorizer evaluates the policy file against every call, reloading the file
+// when it changes.
+type Authorizer struct {
+	policyPath string
+	policy     atomic.Pointer[Policy]
+	modTime    time.Time
+	resolve    ResourceResolver
+}
+
+func NewAuthorizer(policyPath string, resolve ResourceResolver) (*Authorizer, error) {
+	info, err := os.Stat(policyPath)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := LoadPolicy(policyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizer := &Authorizer{policyPath: policyPath, modTime: info.ModTime(), resolve: resolve}
+	authorizer.policy.Store(policy)
+	return authorizer, nil
+}
+
+// WatchPolicy polls the policy file and swaps in the new policy when the file
+// changes. An invalid file is logged and the current policy kept. It returns
+// once the context is done.
+func (a *Authorizer) WatchPolicy(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(a.policyPath)
+		if err != nil {
+			log.Printf("failed to check the policy file: %v", err)
+			continue
+		}
+		if info.ModTime().Equal(a.modTime) {
+			continue
+		}
+		a.modTime = info.ModTime()
+
+		policy, err := LoadPolicy(a.policyPath)
+		if err != nil {
+			log.Printf("kept the current policy: %v", err)
+			continue
+		}
+		a.policy.Store(policy)
+		log.Printf("reloaded the policy file %s", a.policyPath)
+	}
+}
+
+// Authorize returns a PermissionDenied error unless the principal in the
+// context may call the method in every project, and on every table, the call
+// touches. Otherwise it returns the context the call proceeds with, see
+// ResourceResolver.
+func (a *Authorizer) Authorize(ctx context.Context, fullMethod string, req any) (context.Context, error) {
+	if IsPublicMethod(fullMethod) {
+		return ctx, nil
+	}
+
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	// a call the resolver does not cover may touch any table
+	ctx, resources, err := a.resolve(ctx, fullMethod, req)
+	if errors.Is(err, ErrUnknownRequest) || (err == nil && len(resources) == 0) {
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("%s is not covered by the policy", fullMethod))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	policy := a.policy.Load()
+	for _, resource := range resources {
+		if !policy.allowsMethod(principal, fullMethod, resource.ProjectId) {
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("%s may not call %s in project %q", principal.Subject, fullMethod, resource.ProjectId))
+		}
+		if resource.TableName != "" && !policy.allowsTable(principal, resource.ProjectId, resource.TableName) {
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("%s may not access table %s in project %q", principal.Subject, resource.TableName, resource.ProjectId))
+		}
+	}
+
+	return ctx, nil
+}
+
+// UnaryServerInterceptor rejects the unary calls the policy does not allow.
+// It must run after the authentication interceptor.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := a.Authorize(ctx, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor rejects the streaming calls the policy does not
+// allow. Streams carry no request up front, the resolver gets a nil one.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		_, err := a.Authorize(ss.Context(), info.FullMethod, nil)
+		if err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
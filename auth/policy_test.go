@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const dropTableMethod = "/schema_management_service.SchemaService/DropTable"
+
+var testPolicy = &Policy{
+	Roles: map[string]RolePolicy{
+		"reader": {Methods: []string{"List*"}},
+		"admin":  {Methods: []string{"*"}, Projects: []string{"acme-*"}},
+		"dropper": {
+			Methods:  []string{dropTableMethod},
+			Projects: []string{"*"},
+		},
+	},
+	Tables: map[string]TablePolicy{
+		"payroll*": {Subjects: []string{"alice"}, Roles: []string{"finance"}},
+		"secrets":  {Roles: []string{"security"}, Projects: []string{"acme-prod"}},
+	},
+}
+
+func TestPolicyAllowsMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		roles      []string
+		fullMethod string
+		projectId  string
+		want       bool
+	}{
+		{name: "bare name pattern", roles: []string{"reader"}, fullMethod: "/schema_management_service.SchemaService/ListTables", want: true},
+		{name: "method not granted", roles: []string{"reader"}, fullMethod: dropTableMethod},
+		{name: "project pattern", roles: []string{"admin"}, fullMethod: dropTableMethod, projectId: "acme-prod", want: true},
+		{name: "other project", roles: []string{"admin"}, fullMethod: dropTableMethod, projectId: "globex"},
+		{name: "default database only matched by star", roles: []string{"admin"}, fullMethod: dropTableMethod},
+		{name: "full method pattern", roles: []string{"dropper"}, fullMethod: dropTableMethod, want: true},
+		{name: "full method pattern of another service", roles: []string{"dropper"}, fullMethod: "/other.Service/DropTable"},
+		{name: "any role grants", roles: []string{"reader", "dropper"}, fullMethod: dropTableMethod, want: true},
+		{name: "unknown role", roles: []string{"unknown"}, fullMethod: dropTableMethod},
+		{name: "no roles", fullMethod: dropTableMethod},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal := &Principal{Subject: "bob", Roles: test.roles}
+			if got := testPolicy.allowsMethod(principal, test.fullMethod, test.projectId); got != test.want {
+				t.Errorf("allowsMethod(%s, %q) = %v, want %v", test.fullMethod, test.projectId, got, test.want)
+			}
+		})
+	}
+}
+
+func TestPolicyAllowsTable(t *testing.T) {
+	tests := []struct {
+		name      string
+		subject   string
+		roles     []string
+		projectId string
+		tableName string
+		want      bool
+	}{
+		{name: "unrestricted table", subject: "bob", tableName: "orders", want: true},
+		{name: "restricted table", subject: "bob", tableName: "payroll_2024"},
+		{name: "allowed subject", subject: "alice", tableName: "payroll_2024", want: true},
+		{name: "allowed role", subject: "bob", roles: []string{"finance"}, tableName: "payroll", want: true},
+		{name: "restricted in the project", subject: "bob", projectId: "acme-prod", tableName: "secrets"},
+		{name: "open in other projects", subject: "bob", projectId: "acme-dev", tableName: "secrets", want: true},
+		{name: "allowed role in the project", subject: "bob", roles: []string{"security"}, projectId: "acme-prod", tableName: "secrets", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			principal := &Principal{Subject: test.subject, Roles: test.roles}
+			if got := testPolicy.allowsTable(principal, test.projectId, test.tableName); got != test.want {
+				t.Errorf("allowsTable(%q, %q) = %v, want %v", test.projectId, test.tableName, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	type resolvedKey struct{}
+	errLookup := status.Error(codes.Internal, "lookup failed")
+
+	tests := []struct {
+		name       string
+		principal  *Principal
+		fullMethod string
+		resources  []Resource
+		resolveErr error
+		wantCode   codes.Code
+	}{
+		{
+			name:       "allowed",
+			principal:  &Principal{Subject: "bob", Roles: []string{"dropper"}},
+			fullMethod: dropTableMethod,
+			resources:  []Resource{{ProjectId: "acme-prod"}, {ProjectId: "acme-prod", TableName: "orders"}},
+		},
+		{
+			name:       "restricted table",
+			principal:  &Principal{Subject: "bob", Roles: []string{"dropper"}},
+			fullMethod: dropTableMethod,
+			resources:  []Resource{{ProjectId: "acme-prod"}, {ProjectId: "acme-prod", TableName: "payroll"}},
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "every project is checked",
+			principal:  &Principal{Subject: "bob", Roles: []string{"admin"}},
+			fullMethod: dropTableMethod,
+			resources:  []Resource{{ProjectId: "acme-prod"}, {ProjectId: "globex"}},
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "unauthenticated",
+			fullMethod: dropTableMethod,
+			resources:  []Resource{{}},
+			wantCode:   codes.Unauthenticated,
+		},
+		{
+			name:       "request the resolver does not cover",
+			principal:  &Principal{Subject: "bob", Roles: []string{"dropper"}},
+			fullMethod: dropTableMethod,
+			resolveErr: ErrUnknownRequest,
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "no resources",
+			principal:  &Principal{Subject: "bob", Roles: []string{"dropper"}},
+			fullMethod: dropTableMethod,
+			wantCode:   codes.PermissionDenied,
+		},
+		{
+			name:       "lookup error",
+			principal:  &Principal{Subject: "bob", Roles: []string{"dropper"}},
+			fullMethod: dropTableMethod,
+			resolveErr: errLookup,
+			wantCode:   codes.Internal,
+		},
+		{
+			name:       "public method",
+			fullMethod: "/grpc.health.v1.Health/Check",
+			resolveErr: ErrUnknownRequest,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authorizer := &Authorizer{resolve: func(ctx context.Context, fullMethod string, req any) (context.Context, []Resource, error) {
+				if test.resolveErr != nil {
+					return nil, nil, test.resolveErr
+				}
+				return context.WithValue(ctx, resolvedKey{}, true), test.resources, nil
+			}}
+			authorizer.policy.Store(testPolicy)
+
+			ctx := context.Background()
+			if test.principal != nil {
+				ctx = WithPrincipal(ctx, test.principal)
+			}
+
+			ctx, err := authorizer.Authorize(ctx, test.fullMethod, nil)
+			if status.Code(err) != test.wantCode {
+				t.Fatalf("Authorize() error = %v, want code %s", err, test.wantCode)
+			}
+			// the handler runs with what the resolver looked up
+			if err == nil && test.resources != nil && ctx.Value(resolvedKey{}) == nil {
+				t.Errorf("Authorize() dropped the context of the resolver")
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{name: "valid policy", content: `{"roles": {"reader": {"methods": ["List*"]}}, "tables": {"payroll*": {"roles": ["finance"]}}}`},
+		{name: "invalid method pattern", content: `{"roles": {"reader": {"methods": ["List["]}}}`, wantErr: true},
+		{name: "invalid role project pattern", content: `{"roles": {"reader": {"methods": ["*"], "projects": ["["]}}}`, wantErr: true},
+		{name: "invalid table pattern", content: `{"tables": {"[": {"roles": ["finance"]}}}`, wantErr: true},
+		{name: "invalid table project pattern", content: `{"tables": {"t": {"projects": ["["]}}}`, wantErr: true},
+		{name: "invalid JSON", content: `{"roles": `, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "policy.json")
+			err := os.WriteFile(path, []byte(test.content), 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = LoadPolicy(path)
+			if (err != nil) != test.wantErr {
+				t.Errorf("LoadPolicy() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+
+	_, err := LoadPolicy(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadPolicy() of a missing file error = %v, want %v", err, os.ErrNotExist)
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import "context"
+
+// the ways a caller can authenticate
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// the API key name or the JWT subject
+	Subject string
+	// how the caller authenticated, MethodAPIKey or MethodJWT
+	Method string
+	// the roles granted to the caller
+	Roles []string
+}
+
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the principal.
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal attached by the interceptors, or
+// nil when the request was not authenticated.
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,183 @@
+// Package config loads the service configuration from, in increasing order of
+// precedence, the defaults, an optional YAML file, the environment variables
+// and the command line flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Config struct {
+	// the address the gRPC server listens on
+	ListenAddress string `yaml:"listen_address" env:"LISTEN_ADDRESS"`
+	// the directory whose templates replace the embedded ones of the same
+	// name, empty to use the embedded templates only
+	TemplatesOverrideDir string `yaml:"templates_override_dir" env:"TEMPLATES_OVERRIDE_DIR"`
+	// the host the project database users are allowed to connect from
+	ProjectUserHost string `yaml:"project_user_host" env:"PROJECT_USER_HOST"`
+	// how often the database is pinged for the health service
+	HealthCheckInterval time.Duration `yaml:"health_check_interval" env:"HEALTH_CHECK_INTERVAL"`
+	// how long in-flight calls are drained for on shutdown
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+
+	MySQL         MySQLConfig         `yaml:"mysql"`
+	SystemColumns SystemColumnsConfig `yaml:"system_columns"`
+	Trash         TrashConfig         `yaml:"trash"`
+	Auth          AuthConfig          `yaml:"auth"`
+	TLS           TLSConfig           `yaml:"tls"`
+	Features      FeaturesConfig      `yaml:"features"`
+}
+
+// MySQLConfig holds the connection settings of the default database. The
+// project databases are opened with the same ones.
+type MySQLConfig struct {
+	Host     string `yaml:"host" env:"MYSQL_HOST"`
+	Port     string `yaml:"port" env:"MYSQL_PORT"`
+	User     string `yaml:"user" env:"MYSQL_USER"`
+	Password string `yaml:"password" env:"MYSQL_PASSWORD"`
+	Database string `yaml:"database" env:"MYSQL_DATABASE"`
+	// the DSN query parameters, e.g. timeout=5s&readTimeout=30s&parseTime=true
+	DSNParams       string        `yaml:"dsn_params" env:"MYSQL_DSN_PARAMS"`
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"MYSQL_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"MYSQL_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"MYSQL_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"MYSQL_CONN_MAX_IDLE_TIME"`
+}
+
+// SystemColumnsConfig describes the system columns added to every table and
+// the users table they point at.
+type SystemColumnsConfig struct {
+	IdentitySchema string   `yaml:"identity_schema" env:"IDENTITY_SCHEMA"`
+	UsersTable     string   `yaml:"users_table" env:"USERS_TABLE"`
+	PrimaryKeyType string   `yaml:"primary_key_type" env:"PRIMARY_KEY_TYPE"`
+	AuditColumns   []string `yaml:"audit_columns" env:"AUDIT_COLUMNS"`
+	// the primary key type of the users table, which the identity service
+	// owns and may key differently from the project tables
+	UsersPrimaryKeyType string `yaml:"users_primary_key_type" env:"USERS_PRIMARY_KEY_TYPE"`
+}
+
+type TrashConfig struct {
+	// how long trashed tables and columns are kept, 0 keeps them forever
+	Retention     time.Duration `yaml:"retention" env:"TRASH_RETENTION"`
+	PurgeInterval time.Duration `yaml:"purge_interval" env:"TRASH_PURGE_INTERVAL"`
+}
+
+type AuthConfig struct {
+	// serve every call without credentials, for local development only
+	Disabled             bool          `yaml:"disabled" env:"AUTH_DISABLED"`
+	APIKeysFile          string        `yaml:"api_keys_file" env:"AUTH_API_KEYS_FILE"`
+	JWKSFile             string        `yaml:"jwks_file" env:"AUTH_JWKS_FILE"`
+	JWTIssuer            string        `yaml:"jwt_issuer" env:"AUTH_JWT_ISSUER"`
+	JWTAudience          string        `yaml:"jwt_audience" env:"AUTH_JWT_AUDIENCE"`
+	PolicyFile           string        `yaml:"policy_file" env:"AUTH_POLICY_FILE"`
+	PolicyReloadInterval time.Duration `yaml:"policy_reload_interval" env:"AUTH_POLICY_RELOAD_INTERVAL"`
+}
+
+// TLSConfig enables TLS when a certificate is set, and mutual TLS when a
+// client CA bundle is set too.
+type TLSConfig struct {
+	CertFile       string        `yaml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile        string        `yaml:"key_file" env:"TLS_KEY_FILE"`
+	ClientCAFile   string        `yaml:"client_ca_file" env:"TLS_CLIENT_CA_FILE"`
+	ReloadInterval time.Duration `yaml:"reload_interval" env:"TLS_RELOAD_INTERVAL"`
+}
+
+// FeaturesConfig toggles the optional parts of the service.
+type FeaturesConfig struct {
+	// register the gRPC server reflection service
+	Reflection bool `yaml:"reflection" env:"FEATURE_REFLECTION"`
+	// serve CreateDatabase, ListDatabases and DropDatabase
+	DatabaseProvisioning bool `yaml:"database_provisioning" env:"FEATURE_DATABASE_PROVISIONING"`
+	// serve CloneTable and CloneSchema
+	SchemaCloning bool `yaml:"schema_cloning" env:"FEATURE_SCHEMA_CLONING"`
+}
+
+// Default returns the configuration used for the settings left unset.
+func Default() *Config {
+	return &Config{
+		ListenAddress:       ":8084",
+		ProjectUserHost:     "%",
+		HealthCheckInterval: 10 * time.Second,
+		ShutdownTimeout:     30 * time.Second,
+		MySQL: MySQLConfig{
+			Host:            "127.0.0.1",
+			Port:            "3306",
+			DSNParams:       "timeout=5s",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		SystemColumns: SystemColumnsConfig{
+			IdentitySchema:      "baas-system",
+			UsersTable:          "users",
+			PrimaryKeyType:      "BIGINT_UNSIGNED",
+			UsersPrimaryKeyType: "BIGINT_UNSIGNED",
+			AuditColumns:        []string{"creator_id", "created_at", "updated_at"},
+		},
+		Trash: TrashConfig{
+			Retention:     720 * time.Hour,
+			PurgeInterval: time.Hour,
+		},
+		Auth: AuthConfig{
+			PolicyReloadInterval: 10 * time.Second,
+		},
+		TLS: TLSConfig{
+			ReloadInterval: time.Minute,
+		},
+		Features: FeaturesConfig{
+			Reflection:           true,
+			DatabaseProvisioning: true,
+			SchemaCloning:        true,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once. The system columns are
+// checked when the system configuration is built from them.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.ListenAddress != "", "listen_address is required")
+	check(c.ProjectUserHost != "", "project_user_host is required")
+	check(c.HealthCheckInterval > 0, "health_check_interval must be positive")
+	check(c.ShutdownTimeout > 0, "shutdown_timeout must be positive")
+
+	check(c.MySQL.Host != "", "mysql.host is required")
+	check(c.MySQL.Port != "", "mysql.port is required")
+	check(c.MySQL.User != "", "mysql.user is required")
+	check(c.MySQL.Database != "", "mysql.database is required")
+	check(c.MySQL.MaxOpenConns >= 0, "mysql.max_open_conns must not be negative")
+	check(c.MySQL.MaxIdleConns >= 0, "mysql.max_idle_conns must not be negative")
+	check(c.MySQL.ConnMaxLifetime >= 0, "mysql.conn_max_lifetime must not be negative")
+	check(c.MySQL.ConnMaxIdleTime >= 0, "mysql.conn_max_idle_time must not be negative")
+	// let the driver reject unknown or malformed parameters up front
+	_, err := mysql.ParseDSN("/?" + c.MySQL.DSNParams)
+	check(err == nil, "mysql.dsn_params is invalid: %v", err)
+
+	check(c.Trash.Retention >= 0, "trash.retention must not be negative")
+	check(c.Trash.PurgeInterval > 0, "trash.purge_interval must be positive")
+
+	if !c.Auth.Disabled {
+		check(c.Auth.APIKeysFile != "" || c.Auth.JWKSFile != "", "auth.api_keys_file or auth.jwks_file is required unless auth.disabled is set")
+		// without a policy every authenticated caller could call everything
+		check(c.Auth.PolicyFile != "", "auth.policy_file is required unless auth.disabled is set")
+		check(c.Auth.PolicyReloadInterval > 0, "auth.policy_reload_interval must be positive")
+	}
+
+	if c.TLS.CertFile != "" || c.TLS.KeyFile != "" || c.TLS.ClientCAFile != "" {
+		check(c.TLS.CertFile != "" && c.TLS.KeyFile != "", "tls.cert_file and tls.key_file must be set together")
+		check(c.TLS.ReloadInterval > 0, "tls.reload_interval must be positive")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load builds the configuration from the defaults, the YAML file named by the
+// -config flag or CONFIG_FILE, the environment variables and the flags, then
+// validates it. Every setting has a flag named after its YAML path, e.g.
+// -mysql.host. Empty variables count as unset. With GO_ENV=development the
+// variables of a .env file are loaded first, when there is one.
+func Load(args []string) (*Config, error) {
+	config := Default()
+
+	// collect the flags first, they are applied last
+	flagSet := flag.NewFlagSet("schema-service", flag.ContinueOnError)
+	configFile := flagSet.String("config", "", "the YAML configuration file, defaults to CONFIG_FILE")
+	flagValues := make(map[string]string)
+	walkSettings(reflect.ValueOf(config).Elem(), "", func(path, env string, _ reflect.Value) {
+		flagSet.Func(path, fmt.Sprintf("overrides %s", env), func(value string) error {
+			flagValues[path] = value
+			return nil
+		})
+	})
+	err := flagSet.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv("GO_ENV") == "development" {
+		err = godotenv.Load()
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to load .env: %w", err)
+		}
+	}
+
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
+	if *configFile != "" {
+		err = loadFile(config, *configFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	walkSettings(reflect.ValueOf(config).Elem(), "", func(path, env string, field reflect.Value) {
+		// a blank variable, e.g. from a copied .env, must not clear the YAML
+		if value := os.Getenv(env); value != "" {
+			if err := setSetting(field, value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %w", env, err))
+			}
+		}
+		if value, ok := flagValues[path]; ok {
+			if err := setSetting(field, value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid -%s: %w", path, err))
+			}
+		}
+	})
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadFile overlays the settings of the YAML file on the configuration.
+// Unknown keys are rejected so typos do not go unnoticed.
+func loadFile(config *Config, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	err = decoder.Decode(config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	return nil
+}
+
+// walkSettings calls fn for every setting of the struct, with its dotted YAML
+// path and its environment variable.
+func walkSettings(v reflect.Value, prefix string, fn func(path, env string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		path := prefix + strings.Split(field.Tag.Get("yaml"), ",")[0]
+
+		if field.Type.Kind() == reflect.Struct {
+			walkSettings(v.Field(i), path+".", fn)
+			continue
+		}
+		fn(path, field.Tag.Get("env"), v.Field(i))
+	}
+}
+
+// setSetting parses the value into the setting. Lists are comma separated.
+// An empty value leaves numbers, durations and booleans unchanged.
+func setSetting(field reflect.Value, value string) error {
+	if value == "" && field.Kind() != reflect.String && field.Kind() != reflect.Slice {
+		return nil
+	}
+
+	switch {
+	case field.Type() == durationType:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+	case field.Kind() == reflect.String:
+		field.SetString(value)
+	case field.Kind() == reflect.Int:
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(number))
+	case field.Kind() == reflect.Bool:
+		boolean, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(boolean)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var values []string
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		field.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
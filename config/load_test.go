@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfigFile writes a configuration file holding the settings Validate
+// requires, with authentication disabled, the MySQL host when set and the
+// extra top-level settings.
+func writeConfigFile(t *testing.T, host, extra string) string {
+	t.Helper()
+
+	content := "mysql:\n  user: service\n  database: schemas\n"
+	if host != "" {
+		content += "  host: " + host + "\n"
+	}
+	content += "auth:\n  disabled: true\n" + extra
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clearEnv keeps the environment of the machine out of the test.
+func clearEnv(t *testing.T) {
+	for _, env := range []string{"GO_ENV", "CONFIG_FILE", "LISTEN_ADDRESS", "SHUTDOWN_TIMEOUT", "MYSQL_HOST", "MYSQL_USER", "MYSQL_DATABASE", "MYSQL_MAX_OPEN_CONNS", "AUTH_DISABLED"} {
+		t.Setenv(env, "")
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileHost  string
+		fileExtra string
+		env       map[string]string
+		args      []string
+		wantHost  string
+		wantErr   bool
+	}{
+		{
+			name:     "default",
+			wantHost: "127.0.0.1",
+		},
+		{
+			name:     "file over default",
+			fileHost: "file-host",
+			wantHost: "file-host",
+		},
+		{
+			name:     "environment over file",
+			fileHost: "file-host",
+			env:      map[string]string{"MYSQL_HOST": "env-host"},
+			wantHost: "env-host",
+		},
+		{
+			name:     "flag over environment",
+			fileHost: "file-host",
+			env:      map[string]string{"MYSQL_HOST": "env-host"},
+			args:     []string{"-mysql.host", "flag-host"},
+			wantHost: "flag-host",
+		},
+		{
+			name:     "empty variable keeps the file",
+			fileHost: "file-host",
+			env:      map[string]string{"MYSQL_HOST": ""},
+			wantHost: "file-host",
+		},
+		{
+			name:    "invalid variable",
+			env:     map[string]string{"MYSQL_MAX_OPEN_CONNS": "many"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid flag",
+			args:    []string{"-shutdown_timeout", "soon"},
+			wantErr: true,
+		},
+		{
+			name:      "unknown key in the file",
+			fileExtra: "listen_adress: \":9000\"\n",
+			wantErr:   true,
+		},
+		{
+			name:    "invalid setting",
+			args:    []string{"-listen_address", ""},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for env, value := range test.env {
+				t.Setenv(env, value)
+			}
+			path := writeConfigFile(t, test.fileHost, test.fileExtra)
+
+			config, err := Load(append([]string{"-config", path}, test.args...))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("Load() = %+v, want an error", config)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if config.MySQL.Host != test.wantHost {
+				t.Errorf("Load() mysql.host = %q, want %q", config.MySQL.Host, test.wantHost)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileVariable(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("CONFIG_FILE", writeConfigFile(t, "", "listen_address: \":9000\"\n"))
+
+	config, err := Load(nil)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if config.ListenAddress != ":9000" {
+		t.Errorf("Load() listen_address = %q, want %q", config.ListenAddress, ":9000")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(c *Config)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(c *Config) {}},
+		{name: "auth disabled", modify: func(c *Config) { c.Auth = AuthConfig{Disabled: true} }},
+		{name: "missing user", modify: func(c *Config) { c.MySQL.User = "" }, wantErr: true},
+		{name: "missing database", modify: func(c *Config) { c.MySQL.Database = "" }, wantErr: true},
+		{name: "negative pool size", modify: func(c *Config) { c.MySQL.MaxOpenConns = -1 }, wantErr: true},
+		{name: "invalid DSN parameters", modify: func(c *Config) { c.MySQL.DSNParams = "timeout=soon" }, wantErr: true},
+		{name: "zero shutdown timeout", modify: func(c *Config) { c.ShutdownTimeout = 0 }, wantErr: true},
+		{name: "zero purge interval", modify: func(c *Config) { c.Trash.PurgeInterval = 0 }, wantErr: true},
+		{name: "no credentials", modify: func(c *Config) { c.Auth.APIKeysFile = "" }, wantErr: true},
+		{name: "no policy", modify: func(c *Config) { c.Auth.PolicyFile = "" }, wantErr: true},
+		{name: "zero policy reload interval", modify: func(c *Config) { c.Auth.PolicyReloadInterval = 0 }, wantErr: true},
+		{name: "certificate without key", modify: func(c *Config) { c.TLS.CertFile = "server.crt" }, wantErr: true},
+		{name: "certificate and key", modify: func(c *Config) { c.TLS.CertFile, c.TLS.KeyFile = "server.crt", "server.key" }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := Default()
+			config.MySQL.User = "service"
+			config.MySQL.Database = "schemas"
+			config.Auth.APIKeysFile = "api_keys.json"
+			config.Auth.PolicyFile = "policy.json"
+			test.modify(config)
+
+			err := config.Validate()
+			if (err != nil) != test.wantErr {
+				t.Errorf("Validate() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
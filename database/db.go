@@ -3,29 +3,59 @@ package database
 import (
 	"database/sql"
 	"fmt"
-	"os"
+	"sync"
 
 	_ "github.com/go-sql-driver/mysql"
+
+	"github.com/isaacwassouf/schema-service/config"
 )
 
 type SchemaManagementServiceDB struct {
 	Db *sql.DB
+	// the database the connections use, to scope INFORMATION_SCHEMA queries
+	Name string
+
+	// the server version, queried on first use
+	versionMu sync.Mutex
+	version   string
+}
+
+func NewSchemaManagementServiceDB(config *config.MySQLConfig) (*SchemaManagementServiceDB, error) {
+	return NewSchemaManagementServiceDBForDatabase(config, config.Database)
 }
 
-func NewSchemaManagementServiceDB() (*SchemaManagementServiceDB, error) {
-	// read the environment variables
-	user := os.Getenv("MYSQL_USER")
-	pass := os.Getenv("MYSQL_PASSWORD")
-	host := os.Getenv("MYSQL_HOST")
-	port := os.Getenv("MYSQL_PORT")
-	name := os.Getenv("MYSQL_DATABASE")
-
-	db, err := sql.Open(
-		"mysql",
-		fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, pass, host, port, name),
-	)
+// NewSchemaManagementServiceDBForDatabase connects to the named database with
+// the service credentials and pool settings.
+func NewSchemaManagementServiceDBForDatabase(config *config.MySQLConfig, name string) (*SchemaManagementServiceDB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", config.User, config.Password, config.Host, config.Port, name)
+	if config.DSNParams != "" {
+		dsn += "?" + config.DSNParams
+	}
+
+	db, err := sql.Open("mysql", dsn)
 	if err != nil {
 		return nil, err
 	}
-	return &SchemaManagementServiceDB{Db: db}, nil
+
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+
+	return &SchemaManagementServiceDB{Db: db, Name: name}, nil
+}
+
+// ServerVersion returns the VERSION() of the server, querying it once per
+// connection pool.
+func (d *SchemaManagementServiceDB) ServerVersion() (string, error) {
+	d.versionMu.Lock()
+	defer d.versionMu.Unlock()
+
+	if d.version == "" {
+		err := d.Db.QueryRow("SELECT VERSION()").Scan(&d.version)
+		if err != nil {
+			return "", err
+		}
+	}
+	return d.version, nil
 }
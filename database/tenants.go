@@ -0,0 +1,147 @@
+package database
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/isaacwassouf/schema-service/config"
+)
+
+// ErrTenantNotFound is returned when no database is registered for a project.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// ErrTenantPoolClosed is returned by Get once the pool is closed.
+var ErrTenantPoolClosed = errors.New("tenant pool closed")
+
+// TenantPool keeps one connection pool per tenant database, opened on first
+// use.
+type TenantPool struct {
+	// the connection settings every tenant database is opened with
+	config *config.MySQLConfig
+	// resolve returns the database registered for the project, or
+	// ErrTenantNotFound
+	resolve func(projectId string) (string, error)
+	// prepare runs once on every newly opened tenant database
+	prepare func(tenantDB *SchemaManagementServiceDB) error
+
+	// guards the map only, tenants are opened without holding it so a slow
+	// tenant database does not hold up the others
+	mu      sync.Mutex
+	tenants map[string]*tenant
+	closed  bool
+}
+
+// tenant is the connection pool of a project, ready once the first caller is
+// done opening it.
+type tenant struct {
+	ready    chan struct{}
+	tenantDB *SchemaManagementServiceDB
+	err      error
+}
+
+func NewTenantPool(
+	config *config.MySQLConfig,
+	resolve func(projectId string) (string, error),
+	prepare func(tenantDB *SchemaManagementServiceDB) error,
+) *TenantPool {
+	return &TenantPool{
+		config:  config,
+		resolve: resolve,
+		prepare: prepare,
+		tenants: make(map[string]*tenant),
+	}
+}
+
+// Get returns the connection pool of the project database. Concurrent calls
+// for a project that is not open yet share a single attempt at opening it; a
+// failed attempt is not remembered. It returns ErrTenantPoolClosed once the
+// pool is closed.
+func (p *TenantPool) Get(projectId string) (*SchemaManagementServiceDB, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrTenantPoolClosed
+	}
+	t, ok := p.tenants[projectId]
+	if !ok {
+		t = &tenant{ready: make(chan struct{})}
+		p.tenants[projectId] = t
+	}
+	p.mu.Unlock()
+
+	if ok {
+		<-t.ready
+		return t.tenantDB, t.err
+	}
+
+	t.tenantDB, t.err = p.open(projectId)
+	close(t.ready)
+
+	if t.err != nil {
+		p.mu.Lock()
+		if p.tenants[projectId] == t {
+			delete(p.tenants, projectId)
+		}
+		p.mu.Unlock()
+	}
+
+	return t.tenantDB, t.err
+}
+
+// open resolves, opens and prepares the project database.
+func (p *TenantPool) open(projectId string) (*SchemaManagementServiceDB, error) {
+	databaseName, err := p.resolve(projectId)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantDB, err := NewSchemaManagementServiceDBForDatabase(p.config, databaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.prepare(tenantDB)
+	if err != nil {
+		tenantDB.Db.Close()
+		return nil, err
+	}
+
+	return tenantDB, nil
+}
+
+// Remove closes and forgets the connection pool of the project, if open.
+func (p *TenantPool) Remove(projectId string) error {
+	p.mu.Lock()
+	t, ok := p.tenants[projectId]
+	delete(p.tenants, projectId)
+	p.mu.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	<-t.ready
+	if t.err != nil {
+		return nil
+	}
+	return t.tenantDB.Db.Close()
+}
+
+// Close closes the connection pools of every open tenant database, and keeps
+// Get from opening new ones.
+func (p *TenantPool) Close() error {
+	p.mu.Lock()
+	tenants := p.tenants
+	p.tenants = make(map[string]*tenant)
+	p.closed = true
+	p.mu.Unlock()
+
+	var errs []error
+	for _, t := range tenants {
+		<-t.ready
+		if t.err == nil {
+			errs = append(errs, t.tenantDB.Db.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
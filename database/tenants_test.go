@@ -0,0 +1,180 @@
+package database
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/isaacwassouf/schema-service/config"
+)
+
+// sql.Open does not connect, so the pools open without a server
+var testConfig = &config.MySQLConfig{Host: "127.0.0.1", Port: "3306", User: "test", Database: "test"}
+
+func newTestPool(resolve func(projectId string) (string, error), prepare func(tenantDB *SchemaManagementServiceDB) error) *TenantPool {
+	if prepare == nil {
+		prepare = func(tenantDB *SchemaManagementServiceDB) error { return nil }
+	}
+	return NewTenantPool(testConfig, resolve, prepare)
+}
+
+func TestTenantPoolGet(t *testing.T) {
+	errResolve := errors.New("resolve failed")
+	errPrepare := errors.New("prepare failed")
+
+	tests := []struct {
+		name      string
+		projectId string
+		resolve   func(projectId string) (string, error)
+		prepare   func(tenantDB *SchemaManagementServiceDB) error
+		wantName  string
+		wantErr   error
+	}{
+		{
+			name:      "resolved database",
+			projectId: "p1",
+			resolve:   func(projectId string) (string, error) { return "db_" + projectId, nil },
+			wantName:  "db_p1",
+		},
+		{
+			name:      "unknown project",
+			projectId: "p2",
+			resolve:   func(projectId string) (string, error) { return "", ErrTenantNotFound },
+			wantErr:   ErrTenantNotFound,
+		},
+		{
+			name:      "resolve error",
+			projectId: "p3",
+			resolve:   func(projectId string) (string, error) { return "", errResolve },
+			wantErr:   errResolve,
+		},
+		{
+			name:      "prepare error",
+			projectId: "p4",
+			resolve:   func(projectId string) (string, error) { return "db_" + projectId, nil },
+			prepare:   func(tenantDB *SchemaManagementServiceDB) error { return errPrepare },
+			wantErr:   errPrepare,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := newTestPool(test.resolve, test.prepare)
+			defer pool.Close()
+
+			tenantDB, err := pool.Get(test.projectId)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("Get() error = %v, want %v", err, test.wantErr)
+			}
+			if test.wantErr != nil {
+				return
+			}
+			if tenantDB.Name != test.wantName {
+				t.Errorf("Get() database = %q, want %q", tenantDB.Name, test.wantName)
+			}
+		})
+	}
+}
+
+func TestTenantPoolGetOpensOnce(t *testing.T) {
+	var resolves atomic.Int32
+	pool := newTestPool(func(projectId string) (string, error) {
+		resolves.Add(1)
+		return "db_" + projectId, nil
+	}, nil)
+	defer pool.Close()
+
+	const callers = 20
+	tenantDBs := make([]*SchemaManagementServiceDB, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tenantDB, err := pool.Get("p1")
+			if err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+			tenantDBs[i] = tenantDB
+		}(i)
+	}
+	wg.Wait()
+
+	if got := resolves.Load(); got != 1 {
+		t.Errorf("resolve called %d times, want 1", got)
+	}
+	for _, tenantDB := range tenantDBs {
+		if tenantDB != tenantDBs[0] {
+			t.Fatalf("Get() returned different connection pools for the same project")
+		}
+	}
+}
+
+func TestTenantPoolGetRetriesFailures(t *testing.T) {
+	fail := true
+	pool := newTestPool(func(projectId string) (string, error) {
+		if fail {
+			return "", ErrTenantNotFound
+		}
+		return "db_" + projectId, nil
+	}, nil)
+	defer pool.Close()
+
+	_, err := pool.Get("p1")
+	if !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrTenantNotFound)
+	}
+
+	fail = false
+	_, err = pool.Get("p1")
+	if err != nil {
+		t.Fatalf("Get() after a failed attempt error = %v", err)
+	}
+}
+
+func TestTenantPoolRemove(t *testing.T) {
+	pool := newTestPool(func(projectId string) (string, error) { return "db_" + projectId, nil }, nil)
+	defer pool.Close()
+
+	first, err := pool.Get("p1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	err = pool.Remove("p1")
+	if err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	err = pool.Remove("unknown")
+	if err != nil {
+		t.Fatalf("Remove() of a project that is not open error = %v", err)
+	}
+
+	second, err := pool.Get("p1")
+	if err != nil {
+		t.Fatalf("Get() after Remove() error = %v", err)
+	}
+	if second == first {
+		t.Errorf("Get() after Remove() returned the removed connection pool")
+	}
+}
+
+func TestTenantPoolClose(t *testing.T) {
+	pool := newTestPool(func(projectId string) (string, error) { return "db_" + projectId, nil }, nil)
+
+	_, err := pool.Get("p1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	err = pool.Close()
+	if err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	_, err = pool.Get("p1")
+	if !errors.Is(err, ErrTenantPoolClosed) {
+		t.Errorf("Get() after Close() error = %v, want %v", err, ErrTenantPoolClosed)
+	}
+}
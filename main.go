@@ -4,51 +4,310 @@ import (
 	"bytes"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
 	"strings"
-	"text/template"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 
+	"github.com/isaacwassouf/schema-service/auth"
+	"github.com/isaacwassouf/schema-service/config"
 	db "github.com/isaacwassouf/schema-service/database"
 	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
 	"github.com/isaacwassouf/schema-service/shared"
+	"github.com/isaacwassouf/schema-service/templates"
 	"github.com/isaacwassouf/schema-service/utils"
 )
 
 type Column struct {
-	Name         string
-	Type         string
-	NotNullable  bool
-	IsUnique     bool
-	DefaultValue string
+	Name                 string
+	Type                 string
+	NotNullable          bool
+	IsUnique             bool
+	UniqueConstraintName string
+	DefaultValue         string
+	Charset              string
+	Collation            string
+	Comment              string
 }
 
 type Table struct {
-	TableName    string
-	TableComment string
-	Columns      []Column
-	ForeignKeys  []shared.ForeignKey
+	TableName             string
+	TableComment          string
+	Options               *pb.TableOptions
+	PrimaryKeyColumnType  string
+	PrimaryKeyDefault     string
+	PrimaryKeyColumns     []string
+	CreatorId             bool
+	CreatedAt             bool
+	UpdatedAt             bool
+	UsersIdColumnType     string
+	UsersTableReference   string
+	CreatorForeignKeyName string
+	Columns               []Column
+	ForeignKeys           []shared.ForeignKey
+	UniqueConstraints     []shared.UniqueConstraint
 }
 
 type AddColumnPayload struct {
 	TableName string
 	Column    Column
+	First     bool
+	After     string
 }
 
+// the MySQL error number returned when existing rows violate a check constraint
+const mysqlErrCheckConstraintViolated = 3819
+
+// the number of orphan values reported when a foreign key cannot be added
+const orphanValuesSampleSize = 10
+
+// how long the confirmation token of DropDatabase stays valid
+const dropDatabaseConfirmationTTL = 5 * time.Minute
+
+// the number of project databases purged at once
+const trashPurgeConcurrency = 4
+
 type SchemaManagementService struct {
 	pb.UnimplementedSchemaServiceServer
 	schemaManagementServiceDB *db.SchemaManagementServiceDB
+	tenants                   *db.TenantPool
+	systemConfig              *utils.SystemConfig
+	config                    *config.Config
+	confirmations             *utils.ConfirmationTokens
+	templates                 *templates.Registry
+}
+
+// getProjectId returns the project named in the request metadata, or "" when
+// the request names none and goes to the default database.
+func getProjectId(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	projectIds := md.Get(utils.ProjectIdMetadataKey)
+	if len(projectIds) == 0 {
+		return "", nil
+	}
+	if len(projectIds) > 1 || !utils.IsValidProjectId(projectIds[0]) {
+		return "", status.Error(codes.InvalidArgument, "invalid project id")
+	}
+	return projectIds[0], nil
+}
+
+// getTenantDB returns the database of the project named in the request
+// metadata, or the default database when the request names none.
+func (s *SchemaManagementService) getTenantDB(ctx context.Context) (*db.SchemaManagementServiceDB, error) {
+	projectId, err := getProjectId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if projectId == "" {
+		return s.schemaManagementServiceDB, nil
+	}
+
+	tenantDB, err := s.tenants.Get(projectId)
+	if errors.Is(err, db.ErrTenantNotFound) {
+		return nil, status.Error(codes.NotFound, "project not found")
+	}
+	if err != nil {
+		log.Printf("failed to open the database of project %s: %v", projectId, err)
+		return nil, status.Error(codes.Internal, "failed to open the project database")
+	}
+
+	return tenantDB, nil
+}
+
+// tableNameRequest is implemented by the requests touching the one table they
+// name.
+type tableNameRequest interface {
+	GetTableName() string
+}
+
+// the context keys of what resolveResources looked up, so the handlers act on
+// exactly what the policy authorized instead of looking it up again
+type (
+	dropTablePlanKey struct{}
+	trashEntriesKey  struct{}
+	tableNamesKey    struct{}
+)
+
+// resolveResources returns every project and table the call touches, for the
+// policy to authorize. Each request type is listed explicitly, a new one is
+// denied until it is added here.
+func (s *SchemaManagementService) resolveResources(ctx context.Context, fullMethod string, req any) (context.Context, []auth.Resource, error) {
+	projectId, err := getProjectId(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	resources := []auth.Resource{{ProjectId: projectId}}
+	addTables := func(tableNames ...string) {
+		for _, tableName := range tableNames {
+			if tableName != "" {
+				resources = append(resources, auth.Resource{ProjectId: projectId, TableName: tableName})
+			}
+		}
+	}
+
+	switch in := req.(type) {
+	case nil, *emptypb.Empty:
+		// the streams, and the calls listing what the project holds
+	case *pb.DropColumnRequest, *pb.AddColumnRequest, *pb.ListColumnsRequest,
+		*pb.AlterTableOptionsRequest, *pb.UpdateTableCommentRequest, *pb.UpdateColumnCommentRequest,
+		*pb.GetColumnMetadataRequest, *pb.SetColumnMetadataRequest, *pb.RenameColumnRequest, *pb.DropForeignKeyRequest,
+		*pb.AddCheckConstraintRequest, *pb.DropCheckConstraintRequest, *pb.ListCheckConstraintsRequest,
+		*pb.ListRelationshipsRequest:
+		addTables(in.(tableNameRequest).GetTableName())
+	case *pb.CreateTableRequest:
+		addTables(in.TableName)
+		for _, foreignKey := range in.ForeignKeys {
+			addTables(foreignKey.GetReferenceTableName())
+		}
+	case *pb.DropTableRequest:
+		addTables(in.TableName)
+		// a cascade also alters the tables referencing the dropped one
+		if in.Cascade {
+			tenantDB, err := s.getTenantDB(ctx)
+			if err != nil {
+				return nil, nil, err
+			}
+			dependencies, err := utils.GetDropTablePlan(tenantDB.Db, tenantDB.Name, in.TableName, in.KeepColumns)
+			if err != nil {
+				log.Printf("failed to get the dependencies of table %s: %v", in.TableName, err)
+				return nil, nil, status.Error(codes.Internal, "failed to get table dependencies")
+			}
+			for _, dependency := range dependencies {
+				addTables(dependency.TableName)
+			}
+			ctx = context.WithValue(ctx, dropTablePlanKey{}, dependencies)
+		}
+	case *pb.AddForeignKeyRequest:
+		addTables(in.TableName, in.ForeignKey.GetReferenceTableName())
+	case *pb.CreateRelationshipRequest:
+		addTables(in.SourceTableName, in.TargetTableName, in.JunctionTableName)
+	case *pb.RestoreFromTrashRequest:
+		trashEntries, err := s.lookUpTrashEntries(ctx, in.Id, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, trashEntry := range trashEntries {
+			addTables(trashEntry.Entry.TableName)
+		}
+		ctx = context.WithValue(ctx, trashEntriesKey{}, trashEntries)
+	case *pb.PurgeTrashRequest:
+		trashEntries, err := s.lookUpTrashEntries(ctx, in.Id, in.All)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, trashEntry := range trashEntries {
+			addTables(trashEntry.Entry.TableName)
+		}
+		ctx = context.WithValue(ctx, trashEntriesKey{}, trashEntries)
+	case *pb.CloneTableRequest:
+		addTables(in.TableName, in.NewTableName)
+	case *pb.CloneSchemaRequest:
+		// every table is copied, the restricted ones included, and created
+		// under the same name in the target project
+		tenantDB, err := s.getTenantDB(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		tableNames, err := utils.GetTableNames(tenantDB.Db, tenantDB.Name)
+		if err != nil {
+			log.Printf("failed to list the tables of %s: %v", tenantDB.Name, err)
+			return nil, nil, status.Error(codes.Internal, "failed to list tables")
+		}
+		addTables(tableNames...)
+		resources = append(resources, auth.Resource{ProjectId: in.TargetProjectId})
+		for _, tableName := range tableNames {
+			resources = append(resources, auth.Resource{ProjectId: in.TargetProjectId, TableName: tableName})
+		}
+		ctx = context.WithValue(ctx, tableNamesKey{}, tableNames)
+	case *pb.CreateDatabaseRequest:
+		// the call provisions the project it names, not the routed one
+		resources = []auth.Resource{{ProjectId: in.ProjectId}}
+	case *pb.DropDatabaseRequest:
+		resources = []auth.Resource{{ProjectId: in.ProjectId}}
+	default:
+		return nil, nil, auth.ErrUnknownRequest
+	}
+
+	return ctx, resources, nil
+}
+
+// lookUpTrashEntries returns the trash entry with the id, or every trash
+// entry, as the trash requests only carry ids. An unknown id has none, the
+// call reports it.
+func (s *SchemaManagementService) lookUpTrashEntries(ctx context.Context, id uint64, all bool) ([]*utils.TrashEntryDetails, error) {
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var trashEntries []*utils.TrashEntryDetails
+	if all {
+		trashEntries, err = utils.GetTrashEntries(tenantDB.Db)
+	} else {
+		var trashEntry *utils.TrashEntryDetails
+		trashEntry, err = utils.GetTrashEntry(tenantDB.Db, id)
+		if trashEntry != nil {
+			trashEntries = append(trashEntries, trashEntry)
+		}
+	}
+	if err != nil {
+		log.Printf("failed to get the trash entries: %v", err)
+		return nil, status.Error(codes.Internal, "failed to get trash entry")
+	}
+	return trashEntries, nil
+}
+
+// getTrashEntries returns the trash entries the policy authorized the call on,
+// or looks them up when the call was not authorized against a policy.
+func (s *SchemaManagementService) getTrashEntries(ctx context.Context, id uint64, all bool) ([]*utils.TrashEntryDetails, error) {
+	if trashEntries, ok := ctx.Value(trashEntriesKey{}).([]*utils.TrashEntryDetails); ok {
+		return trashEntries, nil
+	}
+	return s.lookUpTrashEntries(ctx, id, all)
+}
+
+// validateIdentifiers rejects the calls naming a malformed table, column,
+// constraint or index, for every handler at once.
+func validateIdentifiers(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	err := utils.ValidateIdentifiers(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return handler(ctx, req)
 }
 
 func (s *SchemaManagementService) CreateTable(ctx context.Context, in *pb.CreateTableRequest) (*pb.CreateTableResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
 	// Check if the table exists
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if table exists")
 	}
@@ -56,21 +315,47 @@ func (s *SchemaManagementService) CreateTable(ctx context.Context, in *pb.Create
 		return nil, status.Error(codes.AlreadyExists, "table already exists")
 	}
 
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/create_table.tmpl")
+	// get the pre-parsed template
+	createTableTemplate, err := s.templates.Get("create_table")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
+		return nil, status.Error(codes.Internal, "failed to parse table")
 	}
 
-	// create the template from the file
-	createTableTemplate, err := template.New("create_table").Parse(templateFile)
+	// check the table options against what the server supports
+	err = utils.ValidateTableOptions(tenantDB.Db, in.Options)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to parse table")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	err = utils.ValidateTableComment(in.TableComment)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the caller names the primary key columns of a composite key, the other
+	// strategies generate the id column
+	primaryKeyType := s.systemConfig.ResolvePrimaryKeyType(in.PrimaryKeyType)
+	var primaryKeyColumns []string
+	if primaryKeyType == pb.PrimaryKeyType_COMPOSITE {
+		err = utils.ValidatePrimaryKeyColumns(in.PrimaryKeyColumns, in.Columns)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		primaryKeyColumns = in.PrimaryKeyColumns
+	} else if len(in.PrimaryKeyColumns) > 0 {
+		return nil, status.Error(codes.InvalidArgument, "primary key columns require a composite primary key")
 	}
 
+	// the system columns are added by the template
+	auditColumns := s.systemConfig.ResolveAuditColumns(in.ExcludedAuditColumns)
+	systemColumns := utils.NewTableSystemColumns(primaryKeyType, auditColumns)
+
 	// create the columns slice
 	columns := make([]Column, len(in.Columns))
 	for i, column := range in.Columns {
+		if systemColumns.Contains(column.Name) {
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("column %s is reserved", column.Name))
+		}
+
 		var columnType string
 		// map the column type to the SQL type
 		switch column.Type.(type) {
@@ -107,27 +392,72 @@ func (s *SchemaManagementService) CreateTable(ctx context.Context, in *pb.Create
 			return nil, status.Error(codes.InvalidArgument, "invalid column type")
 		}
 
+		// check the charset, collation and comment of the column
+		charset, collation, err := utils.ValidateColumnAttributes(tenantDB.Db, column, columnType)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
 		columns[i] = Column{
 			Name:         column.Name,
 			Type:         columnType,
 			NotNullable:  column.NotNullable,
 			IsUnique:     column.IsUnique,
 			DefaultValue: column.DefaultValue,
+			Charset:      charset,
+			Collation:    collation,
+			Comment:      column.Comment,
+		}
+
+		// name the unique constraint of the column
+		if column.IsUnique {
+			columns[i].UniqueConstraintName, err = utils.ResolveConstraintName(column.UniqueConstraintName, utils.UniqueConstraintPrefix, in.TableName, []string{column.Name})
+			if err != nil {
+				return nil, status.Error(codes.InvalidArgument, err.Error())
+			}
 		}
 	}
 
 	foreignKeys := make([]shared.ForeignKey, len(in.ForeignKeys))
 	for i, fk := range in.ForeignKeys {
+		// get the ordered columns of the foreign key
+		columnNames, referenceColumnNames, err := utils.GetForeignKeyColumnNames(tenantDB.Db, tenantDB.Name, fk, s.systemConfig)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
+		// the system columns and tables are managed by the service
+		for _, columnName := range columnNames {
+			if systemColumns.Contains(columnName) {
+				return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("column %s is reserved", columnName))
+			}
+		}
+		if utils.IsSystemTable(fk.ReferenceTableName) {
+			return nil, status.Error(codes.PermissionDenied, "system tables cannot be referenced")
+		}
+
+		constraintName, err := utils.ResolveConstraintName(fk.ConstraintName, utils.ForeignKeyPrefix, in.TableName, columnNames)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+
 		foreignKeys[i] = shared.ForeignKey{
-			ColumnName:          fk.ColumnName,
-			ReferenceTableName:  fk.ReferenceTableName,
-			ReferenceColumnName: fk.ReferenceColumnName,
+			ConstraintName:       constraintName,
+			ColumnNames:          columnNames,
+			ReferenceTableName:   fk.ReferenceTableName,
+			ReferenceColumnNames: referenceColumnNames,
 		}
 		// map the enums to the string values
 		utils.MapReferentialActionsEnumToString(fk, &foreignKeys[i])
 
+		// the users table lives in the identity schema
+		if s.systemConfig.IsUsersTable(fk.ReferenceTableName) {
+			foreignKeys[i].ReferenceTableName = s.systemConfig.UsersTableReference()
+			continue
+		}
+
 		// Check if the reference table exists
-		referenceTableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, fk.ReferenceTableName)
+		referenceTableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, fk.ReferenceTableName)
 		if err != nil {
 			return nil, status.Error(codes.Internal, "failed to check if reference table exists")
 		}
@@ -135,31 +465,66 @@ func (s *SchemaManagementService) CreateTable(ctx context.Context, in *pb.Create
 			return nil, status.Error(codes.NotFound, "reference table not found")
 		}
 
-		// Check if the reference column exists
-		referenceColumnExists, err := utils.CheckColumnExists(s.schemaManagementServiceDB.Db, fk.ReferenceTableName, fk.ReferenceColumnName)
+		for _, referenceColumnName := range referenceColumnNames {
+			// Check if the reference column exists
+			referenceColumnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, fk.ReferenceTableName, referenceColumnName)
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to check if reference column exists")
+			}
+
+			if !referenceColumnExists {
+				return nil, status.Error(codes.NotFound, fmt.Sprintf("reference column %s not found", referenceColumnName))
+			}
+		}
+	}
+
+	uniqueConstraints := make([]shared.UniqueConstraint, len(in.UniqueConstraints))
+	for i, uniqueConstraint := range in.UniqueConstraints {
+		if len(uniqueConstraint.ColumnNames) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "unique constraint requires at least one column")
+		}
+		constraintName, err := utils.ResolveConstraintName(uniqueConstraint.ConstraintName, utils.UniqueConstraintPrefix, in.TableName, uniqueConstraint.ColumnNames)
 		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to check if reference column exists")
+			return nil, status.Error(codes.InvalidArgument, err.Error())
 		}
 
-		if !referenceColumnExists {
-			return nil, status.Error(codes.NotFound, "reference column not found")
+		uniqueConstraints[i] = shared.UniqueConstraint{
+			ConstraintName: constraintName,
+			ColumnNames:    uniqueConstraint.ColumnNames,
 		}
 	}
 
+	// other engines silently ignore foreign keys
+	hasForeignKeys := auditColumns[pb.AuditColumn_CREATOR_ID] || len(foreignKeys) > 0
+	if in.Options != nil && in.Options.Engine != "" && in.Options.Engine != "InnoDB" && hasForeignKeys {
+		return nil, status.Error(codes.InvalidArgument, "foreign keys require the InnoDB engine")
+	}
+
 	var tableSQL bytes.Buffer
 	// Execute the template and write the output to a string
 	err = createTableTemplate.Execute(&tableSQL, Table{
-		TableName:    in.TableName,
-		Columns:      columns,
-		ForeignKeys:  foreignKeys,
-		TableComment: in.TableComment,
+		TableName:             in.TableName,
+		Options:               in.Options,
+		PrimaryKeyColumnType:  utils.GetPrimaryKeyColumnType(primaryKeyType),
+		PrimaryKeyDefault:     utils.GetPrimaryKeyDefault(primaryKeyType),
+		PrimaryKeyColumns:     primaryKeyColumns,
+		CreatorId:             auditColumns[pb.AuditColumn_CREATOR_ID],
+		CreatedAt:             auditColumns[pb.AuditColumn_CREATED_AT],
+		UpdatedAt:             auditColumns[pb.AuditColumn_UPDATED_AT],
+		UsersIdColumnType:     s.systemConfig.UsersIdColumnType(),
+		UsersTableReference:   s.systemConfig.UsersTableReference(),
+		CreatorForeignKeyName: utils.GetConstraintName(utils.ForeignKeyPrefix, in.TableName, []string{"creator_id"}),
+		Columns:               columns,
+		ForeignKeys:           foreignKeys,
+		UniqueConstraints:     uniqueConstraints,
+		TableComment:          in.TableComment,
 	})
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
 	// Create the table
-	_, err = s.schemaManagementServiceDB.Db.Exec(tableSQL.String())
+	_, err = tenantDB.Db.Exec(tableSQL.String())
 	if err != nil {
 		log.Printf("failed to create table: %v", err)
 		return nil, status.Error(codes.Internal, "failed to create table")
@@ -169,8 +534,19 @@ func (s *SchemaManagementService) CreateTable(ctx context.Context, in *pb.Create
 }
 
 func (s *SchemaManagementService) DropTable(ctx context.Context, in *pb.DropTableRequest) (*pb.DropTableResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
 	// Check if the table exists
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if table exists")
 	}
@@ -178,19 +554,327 @@ func (s *SchemaManagementService) DropTable(ctx context.Context, in *pb.DropTabl
 		return nil, status.Error(codes.NotFound, "table not found")
 	}
 
-	// Drop the table
-	_, err = s.schemaManagementServiceDB.Db.Exec(fmt.Sprintf("DROP TABLE %s", in.TableName))
+	// get the foreign keys that have to be dropped first, in order, unless the
+	// policy already authorized a cascade over them
+	dependencies, ok := ctx.Value(dropTablePlanKey{}).([]*pb.TableDependency)
+	if !ok {
+		dependencies, err = utils.GetDropTablePlan(tenantDB.Db, tenantDB.Name, in.TableName, in.KeepColumns)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get table dependencies")
+		}
+	}
+
+	if in.DryRun {
+		return &pb.DropTableResponse{Message: "dry run, table not dropped", Dependencies: dependencies}, nil
+	}
+
+	if len(dependencies) > 0 && !in.Cascade {
+		var references []string
+		var violations []*errdetails.PreconditionFailure_Violation
+		for _, dependency := range dependencies {
+			// only report the tables referencing the dropped table directly
+			if dependency.ForeignKey.ReferenceTableName != in.TableName {
+				continue
+			}
+
+			reference := fmt.Sprintf("%s(%s)", dependency.TableName, strings.Join(dependency.ForeignKey.ColumnNames, ", "))
+			references = append(references, reference)
+			violations = append(violations, &errdetails.PreconditionFailure_Violation{
+				Type:        "FOREIGN_KEY",
+				Subject:     fmt.Sprintf("%s.%s", dependency.TableName, dependency.ForeignKey.ConstraintName),
+				Description: fmt.Sprintf("%s references %s(%s)", reference, in.TableName, strings.Join(dependency.ForeignKey.ReferenceColumnNames, ", ")),
+			})
+		}
+
+		referencedStatus := status.New(
+			codes.FailedPrecondition,
+			fmt.Sprintf("table is referenced by %s", strings.Join(references, ", ")),
+		)
+		referencedStatusWithDetails, err := referencedStatus.WithDetails(&errdetails.PreconditionFailure{Violations: violations})
+		if err != nil {
+			return nil, referencedStatus.Err()
+		}
+		return nil, referencedStatusWithDetails.Err()
+	}
+
+	// render every statement before the first one runs, MySQL commits each
+	// ALTER and DROP on its own so nothing can be rolled back afterwards
+	foreignKeyDrops, err := s.getForeignKeyDrops(tenantDB, dependencies, in.KeepColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	// a trashed table gives up its own foreign keys: their names must stay
+	// free for a new table of the same name, and dropping a parent must not
+	// reach into the trash. The trash entry keeps the statements adding them
+	// back on restore.
+	var ownForeignKeyDrops []foreignKeyDrop
+	if in.Trash {
+		foreignKeys, err := utils.GetForeignKeys(tenantDB.Db, tenantDB.Name, in.TableName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get foreign key constraints")
+		}
+		ownDependencies := make([]*pb.TableDependency, len(foreignKeys))
+		for i, foreignKey := range foreignKeys {
+			ownDependencies[i] = &pb.TableDependency{TableName: in.TableName, ForeignKey: foreignKey}
+		}
+		ownForeignKeyDrops, err = s.getForeignKeyDrops(tenantDB, ownDependencies, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// the table is renamed instead of dropped in trash mode
+	var trashTableSQL bytes.Buffer
+	trashTableName := utils.GetTrashTableName(time.Now(), in.TableName)
+	if in.Trash {
+		trashTableTemplate, err := s.templates.Get("trash_table")
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to move table to trash")
+		}
+
+		err = trashTableTemplate.Execute(&trashTableSQL, struct {
+			TableName      string
+			TrashTableName string
+		}{
+			TableName:      in.TableName,
+			TrashTableName: trashTableName,
+		})
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to execute template")
+		}
+	}
+
+	// Drop the referencing foreign keys, re-adding them if any of them fails
+	for i, foreignKeyDrop := range foreignKeyDrops {
+		_, err = tenantDB.Db.Exec(foreignKeyDrop.dropConstraintSQL)
+		if err != nil {
+			log.Printf("failed to drop foreign key constraint: %v", err)
+			s.restoreForeignKeys(tenantDB, foreignKeyDrops[:i])
+			return nil, status.Error(codes.Internal, "failed to drop foreign key constraint")
+		}
+	}
+
+	if in.Trash {
+		// Drop the foreign keys of the table itself
+		restoreSQL := make([]string, len(ownForeignKeyDrops))
+		for i, ownForeignKeyDrop := range ownForeignKeyDrops {
+			_, err = tenantDB.Db.Exec(ownForeignKeyDrop.dropConstraintSQL)
+			if err != nil {
+				log.Printf("failed to drop foreign key constraint: %v", err)
+				s.restoreForeignKeys(tenantDB, ownForeignKeyDrops[:i])
+				s.restoreForeignKeys(tenantDB, foreignKeyDrops)
+				return nil, status.Error(codes.Internal, "failed to drop foreign key constraint")
+			}
+			restoreSQL[i] = ownForeignKeyDrop.restoreConstraintSQL
+		}
+
+		// record the trash entry first so the table can always be restored
+		trashEntryId, err := utils.AddTrashEntry(tenantDB.Db, &pb.TrashEntry{
+			Kind:           pb.TrashEntryKind_TABLE,
+			TableName:      in.TableName,
+			TrashTableName: trashTableName,
+		}, "", nil, restoreSQL)
+		if err != nil {
+			log.Printf("failed to record trash entry for %s: %v", trashTableName, err)
+			s.restoreForeignKeys(tenantDB, ownForeignKeyDrops)
+			s.restoreForeignKeys(tenantDB, foreignKeyDrops)
+			return nil, status.Error(codes.Internal, "failed to record trash entry")
+		}
+
+		// Move the table to the trash
+		_, err = tenantDB.Db.Exec(trashTableSQL.String())
+		if err != nil {
+			log.Printf("failed to move table to trash: %v", err)
+			s.deleteTrashEntry(tenantDB, trashEntryId)
+			s.restoreForeignKeys(tenantDB, ownForeignKeyDrops)
+			s.restoreForeignKeys(tenantDB, foreignKeyDrops)
+			return nil, status.Error(codes.Internal, "failed to move table to trash")
+		}
+	} else {
+		// Drop the table
+		_, err = tenantDB.Db.Exec(fmt.Sprintf("DROP TABLE %s", in.TableName))
+		if err != nil {
+			log.Printf("failed to drop table: %v", err)
+			s.restoreForeignKeys(tenantDB, foreignKeyDrops)
+			return nil, status.Error(codes.Internal, "failed to drop table")
+		}
+	}
+
+	// the table is gone, the indexes and columns left behind by the dropped
+	// foreign keys are cleaned up on a best effort basis
+	cleanedUp := true
+	for _, foreignKeyDrop := range foreignKeyDrops {
+		for _, cleanupSQL := range foreignKeyDrop.cleanupSQL {
+			_, err = tenantDB.Db.Exec(cleanupSQL)
+			if err != nil {
+				log.Printf("failed to clean up after foreign key %s: %v", foreignKeyDrop.constraintName, err)
+				cleanedUp = false
+			}
+		}
+	}
+
+	if !in.Trash {
+		// the relationships and column metadata of the table are gone with it
+		s.deleteTableBookkeeping(tenantDB, in.TableName)
+	}
+
+	message := "table dropped"
+	if in.Trash {
+		message = "table moved to trash"
+	}
+	if !cleanedUp {
+		message += ", but some referencing indexes or columns could not be dropped"
+	}
+
+	return &pb.DropTableResponse{Message: message, Dependencies: dependencies}, nil
+}
+
+// foreignKeyDrop holds the statements dropping a foreign key referencing a
+// dropped table, the one adding it back and the ones removing its index and
+// columns once the table is gone.
+type foreignKeyDrop struct {
+	constraintName       string
+	dropConstraintSQL    string
+	restoreConstraintSQL string
+	cleanupSQL           []string
+}
+
+// getForeignKeyDrops renders the statements dropping the foreign keys of the
+// plan, without running any of them.
+func (s *SchemaManagementService) getForeignKeyDrops(tenantDB *db.SchemaManagementServiceDB, dependencies []*pb.TableDependency, keepColumns bool) ([]foreignKeyDrop, error) {
+	dropForeignKeyConstraintTemplate, err := s.templates.Get("drop_foreign_key_constraint")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+	}
+
+	addForeignKeyTemplate, err := s.templates.Get("add_foreign_key_existing_column")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+	}
+
+	dropForeignKeyIndexTemplate, err := s.templates.Get("drop_foreign_key_index")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+	}
+
+	dropForeignKeyColumnTemplate, err := s.templates.Get("drop_foreign_key_column")
 	if err != nil {
-		log.Printf("failed to drop table: %v", err)
-		return nil, status.Error(codes.Internal, "failed to drop table")
+		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+	}
+
+	foreignKeyDrops := make([]foreignKeyDrop, len(dependencies))
+	for i, dependency := range dependencies {
+		foreignKeyDrops[i].constraintName = dependency.ForeignKey.ConstraintName
+
+		var dropForeignKeyConstraintSQL bytes.Buffer
+		err = dropForeignKeyConstraintTemplate.Execute(&dropForeignKeyConstraintSQL, struct {
+			TableName      string
+			ConstraintName string
+		}{
+			TableName:      dependency.TableName,
+			ConstraintName: dependency.ForeignKey.ConstraintName,
+		})
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to execute template")
+		}
+		foreignKeyDrops[i].dropConstraintSQL = dropForeignKeyConstraintSQL.String()
+
+		// the columns are still indexed when the constraint is added back
+		var addForeignKeySQL bytes.Buffer
+		err = addForeignKeyTemplate.Execute(&addForeignKeySQL, struct {
+			TableName            string
+			ConstraintName       string
+			IndexName            string
+			ColumnNames          []string
+			ReferenceTableName   string
+			ReferenceColumnNames []string
+			CreateIndex          bool
+			OnUpdate             string
+			OnDelete             string
+		}{
+			TableName:            dependency.TableName,
+			ConstraintName:       dependency.ForeignKey.ConstraintName,
+			ColumnNames:          dependency.ForeignKey.ColumnNames,
+			ReferenceTableName:   dependency.ForeignKey.ReferenceTableName,
+			ReferenceColumnNames: dependency.ForeignKey.ReferenceColumnNames,
+			OnUpdate:             utils.GetReferentialActionsFromEnum(dependency.ForeignKey.OnUpdate),
+			OnDelete:             utils.GetReferentialActionsFromEnum(dependency.ForeignKey.OnDelete),
+		})
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to execute template")
+		}
+		foreignKeyDrops[i].restoreConstraintSQL = addForeignKeySQL.String()
+
+		// get the index backing the foreign key, if nothing else needs it
+		foreignKeyIndex, err := utils.GetUnneededForeignKeyIndex(tenantDB.Db, tenantDB.Name, dependency.TableName, dependency.ForeignKey.ConstraintName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get foreign key index")
+		}
+
+		if foreignKeyIndex != "" {
+			var dropForeignKeyIndexSQL bytes.Buffer
+			err = dropForeignKeyIndexTemplate.Execute(&dropForeignKeyIndexSQL, struct {
+				TableName string
+				IndexName string
+			}{
+				TableName: dependency.TableName,
+				IndexName: foreignKeyIndex,
+			})
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to execute template")
+			}
+			foreignKeyDrops[i].cleanupSQL = append(foreignKeyDrops[i].cleanupSQL, dropForeignKeyIndexSQL.String())
+		}
+
+		if keepColumns {
+			continue
+		}
+
+		for _, columnName := range dependency.ForeignKey.ColumnNames {
+			var dropForeignKeyColumnSQL bytes.Buffer
+			err = dropForeignKeyColumnTemplate.Execute(&dropForeignKeyColumnSQL, struct {
+				TableName  string
+				ColumnName string
+			}{
+				TableName:  dependency.TableName,
+				ColumnName: columnName,
+			})
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to execute template")
+			}
+			foreignKeyDrops[i].cleanupSQL = append(foreignKeyDrops[i].cleanupSQL, dropForeignKeyColumnSQL.String())
+		}
 	}
 
-	return &pb.DropTableResponse{Message: "table dropped"}, nil
+	return foreignKeyDrops, nil
+}
+
+// restoreForeignKeys adds back the dropped foreign key constraints, in reverse
+// order. Failures are only logged, the caller is already failing.
+func (s *SchemaManagementService) restoreForeignKeys(tenantDB *db.SchemaManagementServiceDB, foreignKeyDrops []foreignKeyDrop) {
+	for i := len(foreignKeyDrops) - 1; i >= 0; i-- {
+		_, err := tenantDB.Db.Exec(foreignKeyDrops[i].restoreConstraintSQL)
+		if err != nil {
+			log.Printf("failed to restore foreign key %s: %v", foreignKeyDrops[i].constraintName, err)
+		}
+	}
 }
 
 func (s *SchemaManagementService) DropColumn(ctx context.Context, in *pb.DropColumnRequest) (*pb.DropColumnResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
 	// Check if the table exists
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if table exists")
 	}
@@ -198,8 +882,17 @@ func (s *SchemaManagementService) DropColumn(ctx context.Context, in *pb.DropCol
 		return nil, status.Error(codes.NotFound, "table not found")
 	}
 
+	// the system columns are managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if systemColumns.Contains(in.ColumnName) {
+		return nil, status.Error(codes.PermissionDenied, "system columns cannot be dropped")
+	}
+
 	// Check if the column exists
-	columnExists, err := utils.CheckColumnExists(s.schemaManagementServiceDB.Db, in.TableName, in.ColumnName)
+	columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, in.ColumnName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if column exists")
 	}
@@ -207,14 +900,8 @@ func (s *SchemaManagementService) DropColumn(ctx context.Context, in *pb.DropCol
 		return nil, status.Error(codes.NotFound, "column not found")
 	}
 
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/drop_column.tmpl")
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
-	}
-
-	// create the template from the file
-	dropColumnTemplate, err := template.New("create_table").Parse(templateFile)
+	// get the pre-parsed template
+	dropColumnTemplate, err := s.templates.Get("drop_column")
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to drop column")
 	}
@@ -232,46 +919,158 @@ func (s *SchemaManagementService) DropColumn(ctx context.Context, in *pb.DropCol
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
+	if in.Trash {
+		return s.trashColumn(tenantDB, in, dropColumnSQL.String())
+	}
+
 	// Drop the column
-	_, err = s.schemaManagementServiceDB.Db.Exec(dropColumnSQL.String())
+	_, err = tenantDB.Db.Exec(dropColumnSQL.String())
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to drop column")
 	}
 
-	return &pb.DropColumnResponse{Message: "column dropped"}, nil
-}
-
-func (s *SchemaManagementService) AddColumn(ctx context.Context, in *pb.AddColumnRequest) (*pb.AddColumnResponse, error) {
-	// Check if the table exists
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+	// the metadata of the column is gone with it
+	err = utils.DeleteColumnMetadata(tenantDB.Db, in.TableName, in.ColumnName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to check if table exists")
+		log.Printf("failed to delete the metadata of %s.%s: %v", in.TableName, in.ColumnName, err)
 	}
-	if !tableExists {
-		return nil, status.Error(codes.NotFound, "table not found")
+
+	// so are the relationships it belongs to
+	err = utils.DeleteColumnRelationships(tenantDB.Db, in.TableName, in.ColumnName)
+	if err != nil {
+		log.Printf("failed to delete the relationships of %s.%s: %v", in.TableName, in.ColumnName, err)
 	}
 
-	// Check if the column exists
-	columnExists, err := utils.CheckColumnExists(s.schemaManagementServiceDB.Db, in.TableName, in.Column.Name)
+	return &pb.DropColumnResponse{Message: "column dropped"}, nil
+}
+
+// trashColumn copies the column data, keyed by the primary key, to a side
+// table before dropping the column so it can be restored later.
+func (s *SchemaManagementService) trashColumn(tenantDB *db.SchemaManagementServiceDB, in *pb.DropColumnRequest, dropColumnSQL string) (*pb.DropColumnResponse, error) {
+	// the primary key is needed to put the data back in place
+	keyColumns, err := utils.GetPrimaryKeyColumns(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to check if column exists")
+		return nil, status.Error(codes.Internal, "failed to get primary key columns")
 	}
-	if columnExists {
-		return nil, status.Error(codes.AlreadyExists, "column already exists")
+	if len(keyColumns) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "table has no primary key")
+	}
+	for _, keyColumn := range keyColumns {
+		if strings.EqualFold(keyColumn, in.ColumnName) {
+			return nil, status.Error(codes.FailedPrecondition, "primary key columns cannot be moved to trash")
+		}
 	}
 
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/add_column.tmpl")
+	// get the column definition to recreate the column on restore
+	columnDefinition, err := utils.GetColumnDefinition(tenantDB.Db, tenantDB.Name, in.TableName, in.ColumnName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
+		return nil, status.Error(codes.Internal, "failed to get column definition")
+	}
+	if columnDefinition.Generated {
+		return nil, status.Error(codes.FailedPrecondition, "generated columns hold no data to move to trash, drop them instead")
 	}
 
-	// create the template from the file
-	addColumnTemplate, err := template.New("create_table").Funcs(template.FuncMap{
-		"HasPrefix": strings.HasPrefix,
-	}).Parse(templateFile)
+	// get the pre-parsed template
+	trashColumnTemplate, err := s.templates.Get("trash_column")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to add column")
+		return nil, status.Error(codes.Internal, "failed to move column to trash")
+	}
+
+	// Execute the template and write the output to a string
+	trashTableName := utils.GetTrashTableName(time.Now(), in.TableName, in.ColumnName)
+	var trashColumnSQL bytes.Buffer
+	err = trashColumnTemplate.Execute(&trashColumnSQL, struct {
+		TableName      string
+		ColumnName     string
+		TrashTableName string
+		KeyColumns     []string
+	}{
+		TableName:      in.TableName,
+		ColumnName:     in.ColumnName,
+		TrashTableName: trashTableName,
+		KeyColumns:     keyColumns,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// record the trash entry first so the column can always be restored
+	trashEntryId, err := utils.AddTrashEntry(tenantDB.Db, &pb.TrashEntry{
+		Kind:           pb.TrashEntryKind_COLUMN,
+		TableName:      in.TableName,
+		ColumnName:     in.ColumnName,
+		TrashTableName: trashTableName,
+	}, columnDefinition.String(), keyColumns, nil)
+	if err != nil {
+		log.Printf("failed to record trash entry for %s: %v", trashTableName, err)
+		return nil, status.Error(codes.Internal, "failed to record trash entry")
+	}
+
+	// Copy the column data to the side table
+	_, err = tenantDB.Db.Exec(trashColumnSQL.String())
+	if err != nil {
+		log.Printf("failed to copy column data to trash: %v", err)
+		s.dropTrashTable(tenantDB, trashTableName)
+		s.deleteTrashEntry(tenantDB, trashEntryId)
+		return nil, status.Error(codes.Internal, "failed to move column to trash")
+	}
+
+	// Drop the column
+	_, err = tenantDB.Db.Exec(dropColumnSQL)
+	if err != nil {
+		log.Printf("failed to drop column: %v", err)
+		// remove the copy, the column is still in place
+		s.dropTrashTable(tenantDB, trashTableName)
+		s.deleteTrashEntry(tenantDB, trashEntryId)
+		return nil, status.Error(codes.Internal, "failed to drop column")
+	}
+
+	return &pb.DropColumnResponse{Message: "column moved to trash"}, nil
+}
+
+func (s *SchemaManagementService) AddColumn(ctx context.Context, in *pb.AddColumnRequest) (*pb.AddColumnResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	// Check if the table exists
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	// the system columns are managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if systemColumns.Contains(in.Column.Name) {
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("column %s is reserved", in.Column.Name))
+	}
+
+	// Check if the column exists
+	columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, in.Column.Name)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if column exists")
+	}
+	if columnExists {
+		return nil, status.Error(codes.AlreadyExists, "column already exists")
+	}
+
+	// get the pre-parsed template
+	addColumnTemplate, err := s.templates.Get("add_column")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to add column")
 	}
 
 	var columnType string
@@ -311,25 +1110,69 @@ func (s *SchemaManagementService) AddColumn(ctx context.Context, in *pb.AddColum
 		return nil, status.Error(codes.InvalidArgument, "invalid column type")
 	}
 
+	// check the charset, collation and comment of the column
+	charset, collation, err := utils.ValidateColumnAttributes(tenantDB.Db, in.Column, columnType)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the column is appended unless a position is given
+	var first bool
+	var after string
+	if in.Position != nil {
+		if in.Position.First && in.Position.After != "" {
+			return nil, status.Error(codes.InvalidArgument, "position must be either first or after a column")
+		}
+		first = in.Position.First
+		after = in.Position.After
+		if after != "" {
+			if !utils.IsValidIdentifier(after) {
+				return nil, status.Error(codes.InvalidArgument, "invalid column name")
+			}
+			afterColumnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, after)
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to check if column exists")
+			}
+			if !afterColumnExists {
+				return nil, status.Error(codes.NotFound, fmt.Sprintf("column %s not found", after))
+			}
+		}
+	}
+
+	// name the unique constraint of the column
+	var uniqueConstraintName string
+	if in.Column.IsUnique {
+		uniqueConstraintName, err = utils.ResolveConstraintName(in.Column.UniqueConstraintName, utils.UniqueConstraintPrefix, in.TableName, []string{in.Column.Name})
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
 	// read the file
 	var addColumnSQL bytes.Buffer
 	// Execute the template and write the output to a string
 	err = addColumnTemplate.Execute(&addColumnSQL, AddColumnPayload{
 		TableName: in.TableName,
 		Column: Column{
-			Name:         in.Column.Name,
-			Type:         columnType,
-			NotNullable:  in.Column.NotNullable,
-			IsUnique:     in.Column.IsUnique,
-			DefaultValue: in.Column.DefaultValue,
+			Name:                 in.Column.Name,
+			Type:                 columnType,
+			NotNullable:          in.Column.NotNullable,
+			IsUnique:             in.Column.IsUnique,
+			UniqueConstraintName: uniqueConstraintName,
+			DefaultValue:         in.Column.DefaultValue,
+			Charset:              charset,
+			Collation:            collation,
+			Comment:              in.Column.Comment,
 		},
+		First: first,
+		After: after,
 	})
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
 	// Add the column
-	_, err = s.schemaManagementServiceDB.Db.Exec(addColumnSQL.String())
+	_, err = tenantDB.Db.Exec(addColumnSQL.String())
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to add column")
 	}
@@ -338,34 +1181,35 @@ func (s *SchemaManagementService) AddColumn(ctx context.Context, in *pb.AddColum
 }
 
 func (s *SchemaManagementService) ListTables(ctx context.Context, in *emptypb.Empty) (*pb.ListTablesResponse, error) {
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/list_tables.tmpl")
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
+		return nil, err
 	}
 
-	// create the template from the file
-	listTablesTemplate, err := template.New("list_tables").Parse(templateFile)
+	// get the pre-parsed template
+	listTablesTemplate, err := s.templates.Get("list_tables")
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to list tables")
 	}
 
-	// get the database name from the env vars
-	dbName := utils.GetEnvVar("MYSQL_DATABASE", "database")
-
 	// Execute the template and write the output to a string
 	var listTablesSQL bytes.Buffer
 	err = listTablesTemplate.Execute(&listTablesSQL, struct {
-		DatabaseName string
+		DatabaseName      string
+		SystemTableNames  []string
+		TrashTablePattern string
 	}{
-		DatabaseName: dbName,
+		DatabaseName:      tenantDB.Name,
+		SystemTableNames:  utils.SystemTableNames(),
+		TrashTablePattern: utils.TrashTablePattern(),
 	})
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
 	// Get the list of tables
-	rows, err := s.schemaManagementServiceDB.Db.Query(listTablesSQL.String())
+	rows, err := tenantDB.Db.Query(listTablesSQL.String())
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to list tables")
 	}
@@ -379,7 +1223,20 @@ func (s *SchemaManagementService) ListTables(ctx context.Context, in *emptypb.Em
 		var tableSize uint64
 		var tableComment sql.NullString
 		var createTime string
-		err := rows.Scan(&tableName, &tableCount, &tableSize, &tableComment, &createTime)
+		var engine, charset, collation, rowFormat sql.NullString
+		var autoIncrement sql.NullInt64
+		err := rows.Scan(
+			&tableName,
+			&tableCount,
+			&tableSize,
+			&tableComment,
+			&createTime,
+			&engine,
+			&charset,
+			&collation,
+			&rowFormat,
+			&autoIncrement,
+		)
 		if err != nil {
 			return nil, status.Error(codes.Internal, "failed to scan table details")
 		}
@@ -389,6 +1246,13 @@ func (s *SchemaManagementService) ListTables(ctx context.Context, in *emptypb.Em
 			TableCount: tableCount,
 			TableSize:  tableSize,
 			CreateTime: createTime,
+			Options: &pb.TableOptions{
+				Engine:        engine.String,
+				Charset:       charset.String,
+				Collation:     collation.String,
+				RowFormat:     rowFormat.String,
+				AutoIncrement: uint64(autoIncrement.Int64),
+			},
 		}
 
 		if tableComment.Valid {
@@ -401,8 +1265,20 @@ func (s *SchemaManagementService) ListTables(ctx context.Context, in *emptypb.Em
 	return &pb.ListTablesResponse{Tables: tables}, nil
 }
 
-func (s *SchemaManagementService) ListColumns(ctx context.Context, in *pb.ListColumnsRequest) (*pb.ListColumnsResponse, error) {
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+func (s *SchemaManagementService) AlterTableOptions(ctx context.Context, in *pb.AlterTableOptionsRequest) (*pb.AlterTableOptionsResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	// Check if the table exists
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if table exists")
 	}
@@ -410,112 +1286,134 @@ func (s *SchemaManagementService) ListColumns(ctx context.Context, in *pb.ListCo
 		return nil, status.Error(codes.NotFound, "table not found")
 	}
 
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/list_columns.tmpl")
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
+	if in.Options == nil || *in.Options == (pb.TableOptions{}) {
+		return nil, status.Error(codes.InvalidArgument, "at least one table option is required")
 	}
 
-	// create the template from the file
-	listColumnsTemplate, err := template.New("list_columns").Parse(templateFile)
+	// check the table options against what the server supports
+	err = utils.ValidateTableOptions(tenantDB.Db, in.Options)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to list columns")
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if in.ConvertColumns && in.Options.Charset == "" {
+		return nil, status.Error(codes.InvalidArgument, "converting the columns requires a charset or collation")
+	}
+
+	// the foreign keys on and to the table only work with InnoDB
+	if in.Options.Engine != "" && in.Options.Engine != "InnoDB" {
+		foreignKeys, err := utils.GetForeignKeys(tenantDB.Db, tenantDB.Name, in.TableName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list foreign keys")
+		}
+		inboundForeignKeys, err := utils.GetInboundForeignKeys(tenantDB.Db, tenantDB.Name, in.TableName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list inbound foreign keys")
+		}
+		if len(foreignKeys) > 0 || len(inboundForeignKeys) > 0 {
+			return nil, status.Error(codes.FailedPrecondition, "foreign keys require the InnoDB engine")
+		}
 	}
 
-	// get the database name from the env vars
-	dbName := utils.GetEnvVar("MYSQL_DATABASE", "database")
+	// get the pre-parsed template
+	alterTableOptionsTemplate, err := s.templates.Get("alter_table_options")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to alter table options")
+	}
 
 	// Execute the template and write the output to a string
-	var listColumnsSQL bytes.Buffer
-	err = listColumnsTemplate.Execute(&listColumnsSQL, struct {
-		DatabaseName string
+	var alterTableOptionsSQL bytes.Buffer
+	err = alterTableOptionsTemplate.Execute(&alterTableOptionsSQL, struct {
+		TableName      string
+		Options        *pb.TableOptions
+		ConvertColumns bool
 	}{
-		DatabaseName: dbName,
+		TableName:      in.TableName,
+		Options:        in.Options,
+		ConvertColumns: in.ConvertColumns,
 	})
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
-	// execute the query and replace the ? with the table name
-	rows, err := s.schemaManagementServiceDB.Db.Query(listColumnsSQL.String(), in.TableName)
+	// Alter the table options
+	_, err = tenantDB.Db.Exec(alterTableOptionsSQL.String())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to list columns")
+		log.Printf("failed to alter table options: %v", err)
+		return nil, status.Error(codes.Internal, "failed to alter table options")
 	}
-	defer rows.Close()
 
-	var columns []*pb.Column
-	var foreignKeys []*pb.ForeignKey
-	for rows.Next() {
-		var rawColumnDetails shared.RawColumnDetails
-		err := rows.Scan(
-			&rawColumnDetails.ColumnName,
-			&rawColumnDetails.DataType,
-			&rawColumnDetails.ColumnType,
-			&rawColumnDetails.IsNullable,
-			&rawColumnDetails.ColumnDefault,
-			&rawColumnDetails.MaxLength,
-			&rawColumnDetails.Extra,
-			&rawColumnDetails.IsUnique,
-			&rawColumnDetails.IsForeign,
-			&rawColumnDetails.ForeignKey.ReferenceTableName,
-			&rawColumnDetails.ForeignKey.ReferenceColumnName,
-			&rawColumnDetails.ForeignKey.OnUpdate,
-			&rawColumnDetails.ForeignKey.OnDelete,
-			&rawColumnDetails.Scale,
-			&rawColumnDetails.Precision,
-		)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to scan column details")
-		}
+	return &pb.AlterTableOptionsResponse{Message: "table options altered"}, nil
+}
 
-		column, err := utils.GetColumnFromType(&rawColumnDetails)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to get column from type")
-		}
+func (s *SchemaManagementService) UpdateTableComment(ctx context.Context, in *pb.UpdateTableCommentRequest) (*pb.UpdateTableCommentResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		// set the name of the column
-		column.Name = rawColumnDetails.ColumnName
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
 
-		// check if the column is unique
-		if rawColumnDetails.IsUnique {
-			column.IsUnique = true
-		}
+	// Check if the table exists
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
 
-		// check if the column is nullable
-		if rawColumnDetails.IsNullable == "NO" {
-			column.NotNullable = true
-		}
+	err = utils.ValidateTableComment(in.Comment)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 
-		// check if there is a default value
-		if rawColumnDetails.ColumnDefault.Valid {
-			column.DefaultValue = rawColumnDetails.ColumnDefault.String
-		}
+	// get the pre-parsed template
+	updateTableCommentTemplate, err := s.templates.Get("update_table_comment")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to update table comment")
+	}
 
-		if rawColumnDetails.IsForeign {
-			foreignKey := &pb.ForeignKey{
-				ColumnName:          rawColumnDetails.ColumnName,
-				ReferenceTableName:  rawColumnDetails.ForeignKey.ReferenceTableName.String,
-				ReferenceColumnName: rawColumnDetails.ForeignKey.ReferenceColumnName.String,
-			}
+	// Execute the template and write the output to a string
+	var updateTableCommentSQL bytes.Buffer
+	err = updateTableCommentTemplate.Execute(&updateTableCommentSQL, struct {
+		TableName string
+		Comment   string
+	}{
+		TableName: in.TableName,
+		Comment:   in.Comment,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
 
-			// map the referential actions string to the enum
-			utils.MapReferentialActionsStringToEnum(&shared.ForeignKey{
-				OnUpdate: rawColumnDetails.ForeignKey.OnUpdate.String,
-				OnDelete: rawColumnDetails.ForeignKey.OnDelete.String,
-			}, foreignKey)
+	// Update the comment
+	_, err = tenantDB.Db.Exec(updateTableCommentSQL.String())
+	if err != nil {
+		log.Printf("failed to update table comment: %v", err)
+		return nil, status.Error(codes.Internal, "failed to update table comment")
+	}
 
-			foreignKeys = append(foreignKeys, foreignKey)
-		}
+	return &pb.UpdateTableCommentResponse{Message: "table comment updated"}, nil
+}
 
-		// add the column to the columns slice
-		columns = append(columns, column)
+func (s *SchemaManagementService) UpdateColumnComment(ctx context.Context, in *pb.UpdateColumnCommentRequest) (*pb.UpdateColumnCommentResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	return &pb.ListColumnsResponse{Columns: columns, ForeignKeys: foreignKeys}, nil
-}
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
 
-func (s *SchemaManagementService) AddForeignKey(ctx context.Context, in *pb.AddForeignKeyRequest) (*pb.AddForeignKeyResponse, error) {
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+	// Check if the table exists
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if table exists")
 	}
@@ -523,92 +1421,86 @@ func (s *SchemaManagementService) AddForeignKey(ctx context.Context, in *pb.AddF
 		return nil, status.Error(codes.NotFound, "table not found")
 	}
 
-	// check if the column exists
-	columnExists, err := utils.CheckColumnExists(s.schemaManagementServiceDB.Db, in.TableName, in.ForeignKey.ColumnName)
+	// the system columns are managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to check if column exists")
+		return nil, err
 	}
-	if columnExists {
-		return nil, status.Error(codes.AlreadyExists, "column with this name already exists")
+	if systemColumns.Contains(in.ColumnName) {
+		return nil, status.Error(codes.PermissionDenied, "system columns cannot be modified")
 	}
 
-	var columnType string
-	if in.ForeignKey.ReferenceTableName != "users" {
-		// Check if the reference table exists
-		referenceTableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.ForeignKey.ReferenceTableName)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to check if reference table exists")
-		}
-		if !referenceTableExists {
-			return nil, status.Error(codes.NotFound, "reference table not found")
-		}
-
-		// Check if the reference column exists
-		referenceColumnExists, err := utils.CheckColumnExists(s.schemaManagementServiceDB.Db, in.ForeignKey.ReferenceTableName, in.ForeignKey.ReferenceColumnName)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to check if reference column exists")
-		}
-		if !referenceColumnExists {
-			return nil, status.Error(codes.NotFound, "reference column not found")
-		}
+	// Check if the column exists
+	columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, in.ColumnName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if column exists")
+	}
+	if !columnExists {
+		return nil, status.Error(codes.NotFound, "column not found")
+	}
 
-		// get the column type
-		columnType, err = utils.GetColumnTypeFromName(s.schemaManagementServiceDB.Db, in.ForeignKey.ReferenceTableName, in.ForeignKey.ReferenceColumnName)
-		if err != nil {
-			return nil, status.Error(codes.Internal, "failed to get reference column type")
-		}
+	err = utils.ValidateColumnComment(in.Comment)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
 
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/add_foreign_key.tmpl")
+	// MODIFY COLUMN needs the full definition, which is kept as is
+	columnDefinition, err := utils.GetColumnDefinition(tenantDB.Db, tenantDB.Name, in.TableName, in.ColumnName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
+		return nil, status.Error(codes.Internal, "failed to get column definition")
 	}
 
-	// create the template from the file
-	addForeignKeyTemplate, err := template.New("add_foreign_key").Parse(templateFile)
+	// get the pre-parsed template
+	updateColumnCommentTemplate, err := s.templates.Get("update_column_comment")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to add foreign key")
+		return nil, status.Error(codes.Internal, "failed to update column comment")
 	}
 
 	// Execute the template and write the output to a string
-	var addForeignKeySQL bytes.Buffer
-	err = addForeignKeyTemplate.Execute(&addForeignKeySQL, struct {
-		TableName           string
-		ColumnName          string
-		ColumnType          string
-		ReferenceTableName  string
-		ReferenceColumnName string
-		IsNotNull           bool
-		OnUpdate            string
-		OnDelete            string
+	var updateColumnCommentSQL bytes.Buffer
+	err = updateColumnCommentTemplate.Execute(&updateColumnCommentSQL, struct {
+		TableName        string
+		ColumnName       string
+		ColumnDefinition string
+		Comment          string
 	}{
-		TableName:           in.TableName,
-		ColumnName:          in.ForeignKey.ColumnName,
-		ReferenceTableName:  in.ForeignKey.ReferenceTableName,
-		ReferenceColumnName: in.ForeignKey.ReferenceColumnName,
-		ColumnType:          columnType,
-		IsNotNull:           in.NotNullable,
-		OnUpdate:            utils.GetReferentialActionsFromEnum(in.ForeignKey.OnUpdate),
-		OnDelete:            utils.GetReferentialActionsFromEnum(in.ForeignKey.OnDelete),
+		TableName:        in.TableName,
+		ColumnName:       in.ColumnName,
+		ColumnDefinition: columnDefinition.Definition,
+		Comment:          in.Comment,
 	})
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
-	// Add the foreign key
-	_, err = s.schemaManagementServiceDB.Db.Exec(
-		addForeignKeySQL.String(),
-	)
+	// Update the comment
+	_, err = tenantDB.Db.Exec(updateColumnCommentSQL.String())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to add foreign key")
+		log.Printf("failed to update column comment: %v", err)
+		return nil, status.Error(codes.Internal, "failed to update column comment")
 	}
 
-	return &pb.AddForeignKeyResponse{Message: "foreign key added"}, nil
+	return &pb.UpdateColumnCommentResponse{Message: "column comment updated"}, nil
 }
 
-func (s *SchemaManagementService) DropForeignKey(ctx context.Context, in *pb.DropForeignKeyRequest) (*pb.DropForeignKeyResponse, error) {
-	tableExists, err := utils.CheckTableExists(s.schemaManagementServiceDB.Db, in.TableName)
+func (s *SchemaManagementService) RenameColumn(ctx context.Context, in *pb.RenameColumnRequest) (*pb.RenameColumnResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	if in.NewColumnName == "" {
+		return nil, status.Error(codes.InvalidArgument, "new column name is required")
+	}
+
+	// Check if the table exists
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to check if table exists")
 	}
@@ -616,108 +1508,2302 @@ func (s *SchemaManagementService) DropForeignKey(ctx context.Context, in *pb.Dro
 		return nil, status.Error(codes.NotFound, "table not found")
 	}
 
-	// check if the column exists
-	columnExists, err := utils.CheckColumnExists(s.schemaManagementServiceDB.Db, in.TableName, in.ColumnName)
+	// the system columns are managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to check if column exists")
+		return nil, err
 	}
-	if !columnExists {
-		return nil, status.Error(codes.NotFound, "column not found")
+	if systemColumns.Contains(in.ColumnName) || systemColumns.Contains(in.NewColumnName) {
+		return nil, status.Error(codes.PermissionDenied, "system columns cannot be renamed")
 	}
 
-	// get the constraints for the foreign key
-	foreignKeyConstraints, err := utils.GetForeignKeyConstraint(s.schemaManagementServiceDB.Db, in.TableName, in.ColumnName)
+	// Check if the column exists
+	columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, in.ColumnName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to get foreign key constraints")
+		return nil, status.Error(codes.Internal, "failed to check if column exists")
+	}
+	if !columnExists {
+		return nil, status.Error(codes.NotFound, "column not found")
 	}
 
-	// read the file
-	templateFile, err := utils.ReadTemplateFile("templates/drop_foreign_key_constraint.tmpl")
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
+	// a change of case only is still a rename
+	if !strings.EqualFold(in.ColumnName, in.NewColumnName) {
+		newColumnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, in.NewColumnName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if column exists")
+		}
+		if newColumnExists {
+			return nil, status.Error(codes.AlreadyExists, "column already exists")
+		}
 	}
 
-	// create the template from the file
-	dropForeignKeyConstraintTemplate, err := template.New("drop_foreign_key_constraint").Parse(templateFile)
+	// get the pre-parsed template
+	renameColumnTemplate, err := s.templates.Get("rename_column")
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+		return nil, status.Error(codes.Internal, "failed to rename column")
 	}
 
 	// Execute the template and write the output to a string
-	var dropForeignKeyConstraintSQL bytes.Buffer
-	err = dropForeignKeyConstraintTemplate.Execute(&dropForeignKeyConstraintSQL, struct {
-		TableName      string
-		ConstraintName string
+	var renameColumnSQL bytes.Buffer
+	err = renameColumnTemplate.Execute(&renameColumnSQL, struct {
+		TableName     string
+		ColumnName    string
+		NewColumnName string
 	}{
-		TableName:      in.TableName,
-		ConstraintName: foreignKeyConstraints,
+		TableName:     in.TableName,
+		ColumnName:    in.ColumnName,
+		NewColumnName: in.NewColumnName,
 	})
 	if err != nil {
 		return nil, status.Error(codes.Internal, "failed to execute template")
 	}
 
-	// read the file
-	templateFile, err = utils.ReadTemplateFile("templates/drop_foreign_key_column.tmpl")
-	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to read template file")
-	}
-
-	// create the template from the file
-	dropForeignKeyColumnTemplate, err := template.New("drop_foreign_key").Parse(templateFile)
+	// Rename the column
+	_, err = tenantDB.Db.Exec(renameColumnSQL.String())
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+		log.Printf("failed to rename column: %v", err)
+		return nil, status.Error(codes.Internal, "failed to rename column")
 	}
 
-	// Execute the template and write the output to a string
-	var dropForeignKeyColumnSQL bytes.Buffer
-	err = dropForeignKeyColumnTemplate.Execute(&dropForeignKeyColumnSQL, struct {
-		TableName  string
-		ColumnName string
-	}{
-		TableName:  in.TableName,
-		ColumnName: in.ColumnName,
-	})
+	// the metadata and the relationships follow the column
+	err = utils.RenameColumnMetadata(tenantDB.Db, in.TableName, in.ColumnName, in.NewColumnName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to execute template")
+		log.Printf("failed to rename the metadata of %s.%s: %v", in.TableName, in.ColumnName, err)
 	}
 
-	// start a transaction
-	tx, err := s.schemaManagementServiceDB.Db.Begin()
+	err = utils.RenameRelationshipColumn(tenantDB.Db, in.TableName, in.ColumnName, in.NewColumnName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to start transaction")
+		log.Printf("failed to rename %s.%s in its relationships: %v", in.TableName, in.ColumnName, err)
 	}
 
-	defer tx.Rollback()
+	return &pb.RenameColumnResponse{Message: "column renamed"}, nil
+}
 
-	// Drop the foreign key
-	_, err = tx.Exec(dropForeignKeyConstraintSQL.String())
+func (s *SchemaManagementService) ListColumns(ctx context.Context, in *pb.ListColumnsRequest) (*pb.ListColumnsResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to drop foreign key constraint")
+		return nil, err
 	}
 
-	// Drop the foreign key constraint
-	_, err = tx.Exec(dropForeignKeyColumnSQL.String())
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to drop foreign key")
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
 	}
 
-	// commit the transaction
-	err = tx.Commit()
+	// get the columns managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
 	if err != nil {
-		return nil, status.Error(codes.Internal, "failed to commit transaction")
+		return nil, err
 	}
 
-	return &pb.DropForeignKeyResponse{Message: "foreign key dropped"}, nil
-}
-
-func main() {
-	// load the environment variables from the .env file
-	err := utils.LoadEnvVarsFromFile()
+	// get the check constraints of the table, grouped by the columns they reference
+	checkConstraintsByColumn := make(map[string][]*pb.CheckConstraint)
+	checkConstraintsSupported, err := utils.CheckConstraintsSupported(tenantDB)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return nil, status.Error(codes.Internal, "failed to check the server version")
+	}
+	if checkConstraintsSupported {
+		checkConstraints, err := utils.GetCheckConstraints(tenantDB.Db, tenantDB.Name, in.TableName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list check constraints")
+		}
+		for _, checkConstraint := range checkConstraints {
+			for _, columnName := range checkConstraint.ColumnNames {
+				checkConstraintsByColumn[columnName] = append(checkConstraintsByColumn[columnName], checkConstraint)
+			}
+		}
+	}
+
+	// get the pre-parsed template
+	listColumnsTemplate, err := s.templates.Get("list_columns")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list columns")
+	}
+
+	// Execute the template and write the output to a string
+	var listColumnsSQL bytes.Buffer
+	err = listColumnsTemplate.Execute(&listColumnsSQL, struct {
+		DatabaseName string
+	}{
+		DatabaseName: tenantDB.Name,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// the follow-up queries run first so the rows never hold the only
+	// connection of the pool while another query waits for one
+	rows, err := tenantDB.Db.Query(listColumnsSQL.String(), in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list columns")
+	}
+	defer rows.Close()
+
+	var columns []*pb.Column
+	for rows.Next() {
+		var rawColumnDetails shared.RawColumnDetails
+		err := rows.Scan(
+			&rawColumnDetails.ColumnName,
+			&rawColumnDetails.DataType,
+			&rawColumnDetails.ColumnType,
+			&rawColumnDetails.IsNullable,
+			&rawColumnDetails.ColumnDefault,
+			&rawColumnDetails.MaxLength,
+			&rawColumnDetails.Extra,
+			&rawColumnDetails.IsUnique,
+			&rawColumnDetails.Scale,
+			&rawColumnDetails.Precision,
+			&rawColumnDetails.Comment,
+			&rawColumnDetails.Charset,
+			&rawColumnDetails.Collation,
+		)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to scan column details")
+		}
+
+		column, err := utils.GetColumnFromType(&rawColumnDetails)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get column from type")
+		}
+
+		// set the name of the column
+		column.Name = rawColumnDetails.ColumnName
+
+		// check if the column is unique
+		if rawColumnDetails.IsUnique {
+			column.IsUnique = true
+		}
+
+		// check if the column is nullable
+		if rawColumnDetails.IsNullable == "NO" {
+			column.NotNullable = true
+		}
+
+		// check if there is a default value
+		if rawColumnDetails.ColumnDefault.Valid {
+			column.DefaultValue = rawColumnDetails.ColumnDefault.String
+		}
+
+		// attach the check constraints referencing the column
+		column.CheckConstraints = checkConstraintsByColumn[rawColumnDetails.ColumnName]
+
+		// flag the columns managed by the service
+		column.System = systemColumns.Contains(rawColumnDetails.ColumnName)
+
+		// set the description and the string comparison rules
+		column.Comment = rawColumnDetails.Comment
+		column.Charset = rawColumnDetails.Charset.String
+		column.Collation = rawColumnDetails.Collation.String
+
+		// add the column to the columns slice
+		columns = append(columns, column)
+	}
+	if rows.Err() != nil {
+		return nil, status.Error(codes.Internal, "failed to list columns")
+	}
+	rows.Close()
+
+	// attach the UI hints and validation rules of the columns
+	columnMetadata, err := utils.GetColumnMetadata(tenantDB.Db, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get column metadata")
+	}
+	for _, column := range columns {
+		column.Metadata = columnMetadata[column.Name]
+	}
+
+	// get the foreign keys, grouped by constraint
+	foreignKeys, err := utils.GetForeignKeys(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list foreign keys")
+	}
+
+	// get the unique constraints, grouped by constraint
+	uniqueConstraints, err := utils.GetUniqueConstraints(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list unique constraints")
+	}
+
+	return &pb.ListColumnsResponse{
+		Columns:           columns,
+		ForeignKeys:       foreignKeys,
+		UniqueConstraints: uniqueConstraints,
+	}, nil
+}
+
+func (s *SchemaManagementService) GetColumnMetadata(ctx context.Context, in *pb.GetColumnMetadataRequest) (*pb.GetColumnMetadataResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check if the column exists
+	columnExists, err := s.checkMetadataColumnExists(tenantDB, in.TableName, in.ColumnName)
+	if err != nil {
+		return nil, err
+	}
+	if !columnExists {
+		return nil, status.Error(codes.NotFound, "column not found")
+	}
+
+	columnMetadata, err := utils.GetColumnMetadata(tenantDB.Db, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get column metadata")
+	}
+
+	// columns without metadata get an empty one
+	metadata, ok := columnMetadata[in.ColumnName]
+	if !ok {
+		metadata = &pb.ColumnMetadata{}
+	}
+
+	return &pb.GetColumnMetadataResponse{Metadata: metadata}, nil
+}
+
+func (s *SchemaManagementService) SetColumnMetadata(ctx context.Context, in *pb.SetColumnMetadataRequest) (*pb.SetColumnMetadataResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	// Check if the column exists
+	columnExists, err := s.checkMetadataColumnExists(tenantDB, in.TableName, in.ColumnName)
+	if err != nil {
+		return nil, err
+	}
+	if !columnExists {
+		return nil, status.Error(codes.NotFound, "column not found")
+	}
+
+	err = utils.ValidateColumnMetadata(in.Metadata)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	err = utils.SetColumnMetadata(tenantDB.Db, in.TableName, in.ColumnName, in.Metadata)
+	if err != nil {
+		log.Printf("failed to set column metadata: %v", err)
+		return nil, status.Error(codes.Internal, "failed to set column metadata")
+	}
+
+	return &pb.SetColumnMetadataResponse{Message: "column metadata set"}, nil
+}
+
+// checkMetadataColumnExists reports whether the column exists, returning
+// NotFound when the table does not.
+func (s *SchemaManagementService) checkMetadataColumnExists(tenantDB *db.SchemaManagementServiceDB, tableName, columnName string) (bool, error) {
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, tableName)
+	if err != nil {
+		return false, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return false, status.Error(codes.NotFound, "table not found")
+	}
+
+	columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, tableName, columnName)
+	if err != nil {
+		return false, status.Error(codes.Internal, "failed to check if column exists")
+	}
+
+	return columnExists, nil
+}
+
+func (s *SchemaManagementService) AddForeignKey(ctx context.Context, in *pb.AddForeignKeyRequest) (*pb.AddForeignKeyResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	// get the ordered columns of the foreign key
+	columnNames, referenceColumnNames, err := utils.GetForeignKeyColumnNames(tenantDB.Db, tenantDB.Name, in.ForeignKey, s.systemConfig)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the system columns are managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// check if the columns exist
+	for _, columnName := range columnNames {
+		if systemColumns.Contains(columnName) {
+			return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("column %s is reserved", columnName))
+		}
+
+		columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, columnName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if column exists")
+		}
+		if in.UseExistingColumn && !columnExists {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("column %s not found", columnName))
+		}
+		if !in.UseExistingColumn && columnExists {
+			return nil, status.Error(codes.AlreadyExists, fmt.Sprintf("column %s already exists", columnName))
+		}
+	}
+
+	// name the foreign key constraint
+	constraintName, err := utils.ResolveConstraintName(in.ForeignKey.ConstraintName, utils.ForeignKeyPrefix, in.TableName, columnNames)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	columns := make([]Column, len(columnNames))
+	for i, columnName := range columnNames {
+		columns[i].Name = columnName
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.ForeignKey.ReferenceTableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be referenced")
+	}
+
+	referenceTableName := in.ForeignKey.ReferenceTableName
+	if !s.systemConfig.IsUsersTable(in.ForeignKey.ReferenceTableName) {
+		// Check if the reference table exists
+		referenceTableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.ForeignKey.ReferenceTableName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if reference table exists")
+		}
+		if !referenceTableExists {
+			return nil, status.Error(codes.NotFound, "reference table not found")
+		}
+
+		for i, referenceColumnName := range referenceColumnNames {
+			// Check if the reference column exists
+			referenceColumnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.ForeignKey.ReferenceTableName, referenceColumnName)
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to check if reference column exists")
+			}
+			if !referenceColumnExists {
+				return nil, status.Error(codes.NotFound, fmt.Sprintf("reference column %s not found", referenceColumnName))
+			}
+
+			// get the column type
+			columns[i].Type, err = utils.GetColumnTypeFromName(tenantDB.Db, tenantDB.Name, in.ForeignKey.ReferenceTableName, referenceColumnName)
+			if err != nil {
+				return nil, status.Error(codes.Internal, "failed to get reference column type")
+			}
+		}
+	} else {
+		// the users table lives in the identity schema
+		referenceTableName = s.systemConfig.UsersTableReference()
+		columns[0].Type = s.systemConfig.UsersIdColumnType()
+	}
+
+	if in.UseExistingColumn {
+		return s.addForeignKeyToExistingColumn(tenantDB, in, constraintName, columns, referenceTableName, referenceColumnNames)
+	}
+
+	// get the pre-parsed template
+	addForeignKeyTemplate, err := s.templates.Get("add_foreign_key")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to add foreign key")
+	}
+
+	// Execute the template and write the output to a string
+	var addForeignKeySQL bytes.Buffer
+	err = addForeignKeyTemplate.Execute(&addForeignKeySQL, struct {
+		TableName            string
+		ConstraintName       string
+		Columns              []Column
+		ColumnNames          []string
+		ReferenceTableName   string
+		ReferenceColumnNames []string
+		IsNotNull            bool
+		UniqueConstraintName string
+		OnUpdate             string
+		OnDelete             string
+	}{
+		TableName:            in.TableName,
+		ConstraintName:       constraintName,
+		Columns:              columns,
+		ColumnNames:          columnNames,
+		ReferenceTableName:   referenceTableName,
+		ReferenceColumnNames: referenceColumnNames,
+		IsNotNull:            in.NotNullable,
+		OnUpdate:             utils.GetReferentialActionsFromEnum(in.ForeignKey.OnUpdate),
+		OnDelete:             utils.GetReferentialActionsFromEnum(in.ForeignKey.OnDelete),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Add the foreign key
+	_, err = tenantDB.Db.Exec(
+		addForeignKeySQL.String(),
+	)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to add foreign key")
+	}
+
+	return &pb.AddForeignKeyResponse{Message: "foreign key added"}, nil
+}
+
+// addForeignKeyToExistingColumn constrains columns that already hold data,
+// after making sure their types match the referenced columns and that no row
+// points at a missing reference. NotNullable is ignored in this mode.
+func (s *SchemaManagementService) addForeignKeyToExistingColumn(tenantDB *db.SchemaManagementServiceDB, in *pb.AddForeignKeyRequest, constraintName string, referenceColumns []Column, referenceTableName string, referenceColumnNames []string) (*pb.AddForeignKeyResponse, error) {
+	columnNames := make([]string, len(referenceColumns))
+	for i, referenceColumn := range referenceColumns {
+		columnNames[i] = referenceColumn.Name
+
+		// check if the column type matches the referenced column type
+		columnType, err := utils.GetColumnTypeFromName(tenantDB.Db, tenantDB.Name, in.TableName, referenceColumn.Name)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get column type")
+		}
+		if !strings.EqualFold(columnType, referenceColumn.Type) {
+			return nil, status.Error(
+				codes.FailedPrecondition,
+				fmt.Sprintf("column %s type %s does not match reference column type %s", referenceColumn.Name, columnType, referenceColumn.Type),
+			)
+		}
+	}
+
+	if !s.systemConfig.IsUsersTable(in.ForeignKey.ReferenceTableName) {
+		// the referenced columns must be indexed, the users id is the primary key
+		referenceColumnsIndexed, err := utils.CheckColumnsIndexed(tenantDB.Db, tenantDB.Name, in.ForeignKey.ReferenceTableName, referenceColumnNames)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if reference columns are indexed")
+		}
+		if !referenceColumnsIndexed {
+			return nil, status.Error(codes.FailedPrecondition, "reference columns must be indexed")
+		}
+	}
+
+	// create an index on the columns if there is none yet
+	columnsIndexed, err := utils.CheckColumnsIndexed(tenantDB.Db, tenantDB.Name, in.TableName, columnNames)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if columns are indexed")
+	}
+
+	// name the index backing the foreign key
+	indexName, err := utils.ResolveConstraintName(in.IndexName, utils.IndexPrefix, in.TableName, columnNames)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// check that every existing value has a matching reference row
+	orphanValues, err := utils.GetOrphanValues(
+		tenantDB.Db,
+		in.TableName,
+		columnNames,
+		referenceTableName,
+		referenceColumnNames,
+		orphanValuesSampleSize,
+	)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check for orphan rows")
+	}
+	if len(orphanValues) > 0 {
+		violations := make([]*errdetails.PreconditionFailure_Violation, len(orphanValues))
+		for i, value := range orphanValues {
+			violations[i] = &errdetails.PreconditionFailure_Violation{
+				Type:        "ORPHAN_ROW",
+				Subject:     fmt.Sprintf("%s(%s)=%s", in.TableName, strings.Join(columnNames, ", "), value),
+				Description: fmt.Sprintf("value not found in %s(%s)", referenceTableName, strings.Join(referenceColumnNames, ", ")),
+			}
+		}
+
+		orphanStatus := status.New(
+			codes.FailedPrecondition,
+			fmt.Sprintf("existing rows reference missing values: %s", strings.Join(orphanValues, ", ")),
+		)
+		orphanStatusWithDetails, err := orphanStatus.WithDetails(&errdetails.PreconditionFailure{Violations: violations})
+		if err != nil {
+			return nil, orphanStatus.Err()
+		}
+		return nil, orphanStatusWithDetails.Err()
+	}
+
+	// get the pre-parsed template
+	addForeignKeyTemplate, err := s.templates.Get("add_foreign_key_existing_column")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to add foreign key")
+	}
+
+	// Execute the template and write the output to a string
+	var addForeignKeySQL bytes.Buffer
+	err = addForeignKeyTemplate.Execute(&addForeignKeySQL, struct {
+		TableName            string
+		ConstraintName       string
+		IndexName            string
+		ColumnNames          []string
+		ReferenceTableName   string
+		ReferenceColumnNames []string
+		CreateIndex          bool
+		OnUpdate             string
+		OnDelete             string
+	}{
+		TableName:            in.TableName,
+		ConstraintName:       constraintName,
+		IndexName:            indexName,
+		ColumnNames:          columnNames,
+		ReferenceTableName:   referenceTableName,
+		ReferenceColumnNames: referenceColumnNames,
+		CreateIndex:          !columnsIndexed,
+		OnUpdate:             utils.GetReferentialActionsFromEnum(in.ForeignKey.OnUpdate),
+		OnDelete:             utils.GetReferentialActionsFromEnum(in.ForeignKey.OnDelete),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Add the foreign key
+	_, err = tenantDB.Db.Exec(addForeignKeySQL.String())
+	if err != nil {
+		log.Printf("failed to add foreign key: %v", err)
+		return nil, status.Error(codes.Internal, "failed to add foreign key")
+	}
+
+	return &pb.AddForeignKeyResponse{Message: "foreign key added"}, nil
+}
+
+func (s *SchemaManagementService) DropForeignKey(ctx context.Context, in *pb.DropForeignKeyRequest) (*pb.DropForeignKeyResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	// the foreign key is named by its constraint, or by one of its columns
+	if in.ConstraintName == "" && in.ColumnName == "" {
+		return nil, status.Error(codes.InvalidArgument, "constraint name or column name is required")
+	}
+	if in.ConstraintName == "" {
+		columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, in.ColumnName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if column exists")
+		}
+		if !columnExists {
+			return nil, status.Error(codes.NotFound, "column not found")
+		}
+	}
+
+	foreignKeys, err := utils.GetForeignKeys(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get foreign key constraints")
+	}
+	var matches []*pb.ForeignKey
+	for _, foreignKey := range foreignKeys {
+		if in.ConstraintName != "" {
+			if strings.EqualFold(foreignKey.ConstraintName, in.ConstraintName) {
+				matches = append(matches, foreignKey)
+			}
+			continue
+		}
+		for _, columnName := range foreignKey.ColumnNames {
+			if strings.EqualFold(columnName, in.ColumnName) {
+				matches = append(matches, foreignKey)
+				break
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return nil, status.Error(codes.NotFound, "foreign key not found")
+	}
+	if len(matches) > 1 {
+		return nil, status.Error(codes.FailedPrecondition, "column is part of several foreign keys, name the constraint to drop")
+	}
+	foreignKey := matches[0]
+
+	// the creator foreign key is managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TableName)
+	if err != nil {
+		return nil, err
+	}
+	for _, columnName := range foreignKey.ColumnNames {
+		if systemColumns.Contains(columnName) {
+			return nil, status.Error(codes.PermissionDenied, "system foreign keys cannot be dropped")
+		}
+	}
+
+	// render every statement before the first one runs, MySQL commits each
+	// ALTER on its own so nothing can be rolled back afterwards
+	foreignKeyDrops, err := s.getForeignKeyDrops(tenantDB, []*pb.TableDependency{{TableName: in.TableName, ForeignKey: foreignKey}}, in.KeepColumn)
+	if err != nil {
+		return nil, err
+	}
+	foreignKeyDrop := foreignKeyDrops[0]
+
+	// Drop the foreign key
+	_, err = tenantDB.Db.Exec(foreignKeyDrop.dropConstraintSQL)
+	if err != nil {
+		log.Printf("failed to drop foreign key constraint: %v", err)
+		return nil, status.Error(codes.Internal, "failed to drop foreign key constraint")
+	}
+
+	// Drop the index only backing the foreign key, then every column of the
+	// key unless the caller keeps the data. The constraint is added back on
+	// failure as long as no column is gone.
+	columnCount := 0
+	if !in.KeepColumn {
+		columnCount = len(foreignKey.ColumnNames)
+	}
+	restorable := len(foreignKeyDrop.cleanupSQL) - columnCount
+	message := "foreign key dropped"
+	for i, cleanupSQL := range foreignKeyDrop.cleanupSQL {
+		_, err = tenantDB.Db.Exec(cleanupSQL)
+		if err == nil {
+			continue
+		}
+		log.Printf("failed to clean up after foreign key %s: %v", foreignKeyDrop.constraintName, err)
+		if i <= restorable {
+			s.restoreForeignKeys(tenantDB, foreignKeyDrops)
+			return nil, status.Error(codes.Internal, "failed to drop foreign key")
+		}
+		message = "foreign key dropped, but some of its columns could not be dropped"
+		break
+	}
+
+	// the relationships held by the foreign key are gone with it
+	err = utils.DeleteForeignKeyRelationships(tenantDB.Db, in.TableName, foreignKey.ConstraintName)
+	if err != nil {
+		log.Printf("failed to delete the relationships of %s: %v", foreignKey.ConstraintName, err)
+	}
+
+	return &pb.DropForeignKeyResponse{Message: message}, nil
+}
+
+func (s *SchemaManagementService) AddCheckConstraint(ctx context.Context, in *pb.AddCheckConstraintRequest) (*pb.AddCheckConstraintResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	// check if the server enforces check constraints
+	checkConstraintsSupported, err := utils.CheckConstraintsSupported(tenantDB)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check the server version")
+	}
+	if !checkConstraintsSupported {
+		return nil, status.Error(codes.FailedPrecondition, "check constraints require MySQL 8.0.16 or MariaDB 10.2.1 or later")
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	// build the expression from the restricted expression tree
+	expression, columnNames, err := utils.BuildCheckExpression(in.Expression)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// name the check constraint
+	constraintName, err := utils.ResolveConstraintName(in.ConstraintName, utils.CheckConstraintPrefix, in.TableName, columnNames)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// check constraint names are unique per schema
+	constraintTable, err := utils.GetCheckConstraintTable(tenantDB.Db, tenantDB.Name, constraintName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if check constraint exists")
+	}
+	if constraintTable != "" {
+		return nil, status.Error(codes.AlreadyExists, "check constraint already exists")
+	}
+
+	// check if the referenced columns exist
+	for _, columnName := range columnNames {
+		columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TableName, columnName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if column exists")
+		}
+		if !columnExists {
+			return nil, status.Error(codes.NotFound, fmt.Sprintf("column %s not found", columnName))
+		}
+	}
+
+	// get the pre-parsed template
+	addCheckConstraintTemplate, err := s.templates.Get("add_check_constraint")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to add check constraint")
+	}
+
+	// Execute the template and write the output to a string
+	var addCheckConstraintSQL bytes.Buffer
+	err = addCheckConstraintTemplate.Execute(&addCheckConstraintSQL, struct {
+		TableName      string
+		ConstraintName string
+		Expression     string
+	}{
+		TableName:      in.TableName,
+		ConstraintName: constraintName,
+		Expression:     expression,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Add the check constraint
+	_, err = tenantDB.Db.Exec(addCheckConstraintSQL.String())
+	if err != nil {
+		var mysqlErr *mysql.MySQLError
+		if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrCheckConstraintViolated {
+			return nil, status.Error(codes.FailedPrecondition, "existing rows violate the check constraint")
+		}
+		log.Printf("failed to add check constraint: %v", err)
+		return nil, status.Error(codes.Internal, "failed to add check constraint")
+	}
+
+	return &pb.AddCheckConstraintResponse{Message: addCheckConstraintSQL.String()}, nil
+}
+
+func (s *SchemaManagementService) DropCheckConstraint(ctx context.Context, in *pb.DropCheckConstraintRequest) (*pb.DropCheckConstraintResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	// check if the server enforces check constraints
+	checkConstraintsSupported, err := utils.CheckConstraintsSupported(tenantDB)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check the server version")
+	}
+	if !checkConstraintsSupported {
+		return nil, status.Error(codes.FailedPrecondition, "check constraints require MySQL 8.0.16 or MariaDB 10.2.1 or later")
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	if !utils.IsValidIdentifier(in.ConstraintName) {
+		return nil, status.Error(codes.InvalidArgument, "invalid constraint name")
+	}
+
+	// check if the constraint belongs to the table
+	constraintTable, err := utils.GetCheckConstraintTable(tenantDB.Db, tenantDB.Name, in.ConstraintName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if check constraint exists")
+	}
+	if constraintTable != in.TableName {
+		return nil, status.Error(codes.NotFound, "check constraint not found")
+	}
+
+	// get the pre-parsed template
+	dropCheckConstraintTemplate, err := s.templates.Get("drop_check_constraint")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to drop check constraint")
+	}
+
+	// Execute the template and write the output to a string
+	var dropCheckConstraintSQL bytes.Buffer
+	err = dropCheckConstraintTemplate.Execute(&dropCheckConstraintSQL, struct {
+		TableName      string
+		ConstraintName string
+	}{
+		TableName:      in.TableName,
+		ConstraintName: in.ConstraintName,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Drop the check constraint
+	_, err = tenantDB.Db.Exec(dropCheckConstraintSQL.String())
+	if err != nil {
+		log.Printf("failed to drop check constraint: %v", err)
+		return nil, status.Error(codes.Internal, "failed to drop check constraint")
+	}
+
+	return &pb.DropCheckConstraintResponse{Message: "check constraint dropped"}, nil
+}
+
+func (s *SchemaManagementService) ListCheckConstraints(ctx context.Context, in *pb.ListCheckConstraintsRequest) (*pb.ListCheckConstraintsResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// check if the server enforces check constraints
+	checkConstraintsSupported, err := utils.CheckConstraintsSupported(tenantDB)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check the server version")
+	}
+	if !checkConstraintsSupported {
+		return nil, status.Error(codes.FailedPrecondition, "check constraints require MySQL 8.0.16 or MariaDB 10.2.1 or later")
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	checkConstraints, err := utils.GetCheckConstraints(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list check constraints")
+	}
+
+	return &pb.ListCheckConstraintsResponse{CheckConstraints: checkConstraints}, nil
+}
+
+func (s *SchemaManagementService) CreateRelationship(ctx context.Context, in *pb.CreateRelationshipRequest) (*pb.CreateRelationshipResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.SourceTableName) || utils.IsSystemTable(in.TargetTableName) || utils.IsSystemTable(in.JunctionTableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be modified")
+	}
+
+	// get the key referenced on the source side
+	sourceTableName, sourceColumns, err := s.getRelationshipKey(tenantDB, in.SourceTableName)
+	if err != nil {
+		return nil, err
+	}
+
+	relationship := &pb.Relationship{
+		Name:              in.Name,
+		Type:              in.Type,
+		SourceTableName:   in.SourceTableName,
+		TargetTableName:   in.TargetTableName,
+		JunctionTableName: in.JunctionTableName,
+	}
+
+	switch in.Type {
+	case pb.RelationshipType_ONE_TO_ONE, pb.RelationshipType_ONE_TO_MANY:
+		err = s.createForeignKeyRelationship(tenantDB, in, relationship, sourceTableName, sourceColumns)
+	case pb.RelationshipType_MANY_TO_MANY:
+		err = s.createJunctionTableRelationship(tenantDB, in, relationship, sourceTableName, sourceColumns)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "invalid relationship type")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// record the relationship so it can be listed
+	err = utils.AddRelationship(tenantDB.Db, relationship)
+	if err != nil {
+		log.Printf("failed to record relationship %s: %v", relationship.Name, err)
+		s.dropRelationship(tenantDB, relationship)
+		return nil, status.Error(codes.Internal, "failed to record relationship")
+	}
+
+	return &pb.CreateRelationshipResponse{Message: "relationship created", Relationship: relationship}, nil
+}
+
+// dropRelationship removes the junction table, or the foreign key and the
+// columns, of a relationship that could not be recorded. Failures are only
+// logged, the caller is already failing.
+func (s *SchemaManagementService) dropRelationship(tenantDB *db.SchemaManagementServiceDB, relationship *pb.Relationship) {
+	if relationship.JunctionTableName != "" {
+		_, err := tenantDB.Db.Exec(fmt.Sprintf("DROP TABLE %s", relationship.JunctionTableName))
+		if err != nil {
+			log.Printf("failed to drop junction table %s: %v", relationship.JunctionTableName, err)
+		}
+		return
+	}
+
+	// dropping the columns drops the indexes on them
+	foreignKeyDrops, err := s.getForeignKeyDrops(tenantDB, []*pb.TableDependency{{
+		TableName: relationship.TargetTableName,
+		ForeignKey: &pb.ForeignKey{
+			ConstraintName: relationship.ConstraintNames[0],
+			ColumnNames:    relationship.ColumnNames,
+		},
+	}}, false)
+	if err != nil {
+		log.Printf("failed to drop relationship %s: %v", relationship.Name, err)
+		return
+	}
+
+	for _, dropSQL := range append([]string{foreignKeyDrops[0].dropConstraintSQL}, foreignKeyDrops[0].cleanupSQL...) {
+		_, err = tenantDB.Db.Exec(dropSQL)
+		if err != nil {
+			log.Printf("failed to drop relationship %s: %v", relationship.Name, err)
+			return
+		}
+	}
+}
+
+// getRelationshipKey returns the name to reference the table by and its key
+// columns, typed, that the other side of a relationship points at.
+func (s *SchemaManagementService) getRelationshipKey(tenantDB *db.SchemaManagementServiceDB, tableName string) (string, []Column, error) {
+	// the users table lives in the identity schema
+	if s.systemConfig.IsUsersTable(tableName) {
+		return s.systemConfig.UsersTableReference(), []Column{{
+			Name: utils.UsersIdColumnName,
+			Type: s.systemConfig.UsersIdColumnType(),
+		}}, nil
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, tableName)
+	if err != nil {
+		return "", nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return "", nil, status.Error(codes.NotFound, fmt.Sprintf("table %s not found", tableName))
+	}
+
+	keyColumnNames, err := utils.GetPrimaryKeyColumns(tenantDB.Db, tenantDB.Name, tableName)
+	if err != nil {
+		return "", nil, status.Error(codes.Internal, "failed to get primary key columns")
+	}
+	if len(keyColumnNames) == 0 {
+		return "", nil, status.Error(codes.FailedPrecondition, fmt.Sprintf("table %s has no primary key", tableName))
+	}
+
+	keyColumns := make([]Column, len(keyColumnNames))
+	for i, keyColumnName := range keyColumnNames {
+		keyColumns[i].Name = keyColumnName
+		keyColumns[i].Type, err = utils.GetColumnTypeFromName(tenantDB.Db, tenantDB.Name, tableName, keyColumnName)
+		if err != nil {
+			return "", nil, status.Error(codes.Internal, "failed to get primary key column type")
+		}
+	}
+
+	return tableName, keyColumns, nil
+}
+
+// createForeignKeyRelationship adds the columns referencing the source table
+// to the target table, unique for one-to-one relationships.
+func (s *SchemaManagementService) createForeignKeyRelationship(tenantDB *db.SchemaManagementServiceDB, in *pb.CreateRelationshipRequest, relationship *pb.Relationship, sourceTableName string, sourceColumns []Column) error {
+	if s.systemConfig.IsUsersTable(in.TargetTableName) {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("%s cannot be the target of a relationship", in.TargetTableName))
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TargetTableName)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return status.Error(codes.NotFound, fmt.Sprintf("table %s not found", in.TargetTableName))
+	}
+
+	referenceColumnNames := make([]string, len(sourceColumns))
+	for i, sourceColumn := range sourceColumns {
+		referenceColumnNames[i] = sourceColumn.Name
+	}
+
+	// name the columns after the source table
+	columnNames, err := utils.GetRelationshipColumnNames(in.ColumnNames, in.SourceTableName, referenceColumnNames)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// the system columns are managed by the service
+	systemColumns, err := s.getSystemColumns(tenantDB, in.TargetTableName)
+	if err != nil {
+		return err
+	}
+
+	columns := make([]Column, len(columnNames))
+	for i, columnName := range columnNames {
+		if systemColumns.Contains(columnName) {
+			return status.Error(codes.PermissionDenied, fmt.Sprintf("column %s is reserved", columnName))
+		}
+
+		columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, in.TargetTableName, columnName)
+		if err != nil {
+			return status.Error(codes.Internal, "failed to check if column exists")
+		}
+		if columnExists {
+			return status.Error(codes.AlreadyExists, fmt.Sprintf("column %s already exists", columnName))
+		}
+
+		columns[i] = Column{Name: columnName, Type: sourceColumns[i].Type}
+	}
+
+	if relationship.Name == "" {
+		relationship.Name = utils.GetConstraintName(utils.ForeignKeyPrefix, in.TargetTableName, columnNames)
+	}
+	relationshipExists, err := utils.CheckRelationshipExists(tenantDB.Db, relationship.Name)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check if relationship exists")
+	}
+	if relationshipExists {
+		return status.Error(codes.AlreadyExists, "relationship already exists")
+	}
+
+	constraintName := utils.GetConstraintName(utils.ForeignKeyPrefix, in.TargetTableName, columnNames)
+	relationship.ColumnNames = columnNames
+	relationship.ConstraintNames = []string{constraintName}
+
+	// a one-to-one relationship allows each source row to be referenced once
+	var uniqueConstraintName string
+	if in.Type == pb.RelationshipType_ONE_TO_ONE {
+		uniqueConstraintName = utils.GetConstraintName(utils.UniqueConstraintPrefix, in.TargetTableName, columnNames)
+		relationship.ConstraintNames = append(relationship.ConstraintNames, uniqueConstraintName)
+	}
+
+	// get the pre-parsed template
+	addForeignKeyTemplate, err := s.templates.Get("add_foreign_key")
+	if err != nil {
+		return status.Error(codes.Internal, "failed to create relationship")
+	}
+
+	// Execute the template and write the output to a string
+	var addForeignKeySQL bytes.Buffer
+	err = addForeignKeyTemplate.Execute(&addForeignKeySQL, struct {
+		TableName            string
+		ConstraintName       string
+		Columns              []Column
+		ColumnNames          []string
+		ReferenceTableName   string
+		ReferenceColumnNames []string
+		IsNotNull            bool
+		UniqueConstraintName string
+		OnUpdate             string
+		OnDelete             string
+	}{
+		TableName:            in.TargetTableName,
+		ConstraintName:       constraintName,
+		Columns:              columns,
+		ColumnNames:          columnNames,
+		ReferenceTableName:   sourceTableName,
+		ReferenceColumnNames: referenceColumnNames,
+		IsNotNull:            in.NotNullable,
+		UniqueConstraintName: uniqueConstraintName,
+		OnUpdate:             utils.GetReferentialActionsFromEnum(in.OnUpdate),
+		OnDelete:             utils.GetReferentialActionsFromEnum(in.OnDelete),
+	})
+	if err != nil {
+		return status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Add the columns and their constraints
+	_, err = tenantDB.Db.Exec(addForeignKeySQL.String())
+	if err != nil {
+		log.Printf("failed to create relationship: %v", err)
+		return status.Error(codes.Internal, "failed to create relationship")
+	}
+
+	return nil
+}
+
+// createJunctionTableRelationship creates a table keyed by the columns
+// referencing both sides of a many-to-many relationship.
+func (s *SchemaManagementService) createJunctionTableRelationship(tenantDB *db.SchemaManagementServiceDB, in *pb.CreateRelationshipRequest, relationship *pb.Relationship, sourceTableName string, sourceColumns []Column) error {
+	// get the key referenced on the target side
+	targetTableName, targetColumns, err := s.getRelationshipKey(tenantDB, in.TargetTableName)
+	if err != nil {
+		return err
+	}
+
+	junctionTableName := in.JunctionTableName
+	if junctionTableName == "" {
+		junctionTableName = fmt.Sprintf("%s_%s", in.SourceTableName, in.TargetTableName)
+	}
+	if !utils.IsValidIdentifier(junctionTableName) || utils.IsSystemTable(junctionTableName) {
+		return status.Error(codes.InvalidArgument, fmt.Sprintf("invalid junction table name %q", junctionTableName))
+	}
+
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, junctionTableName)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if tableExists {
+		return status.Error(codes.AlreadyExists, fmt.Sprintf("table %s already exists", junctionTableName))
+	}
+
+	sourceReferenceColumnNames := make([]string, len(sourceColumns))
+	for i, sourceColumn := range sourceColumns {
+		sourceReferenceColumnNames[i] = sourceColumn.Name
+	}
+	targetReferenceColumnNames := make([]string, len(targetColumns))
+	for i, targetColumn := range targetColumns {
+		targetReferenceColumnNames[i] = targetColumn.Name
+	}
+
+	// name the columns after the tables they reference
+	sourceColumnNames, err := utils.GetRelationshipColumnNames(in.ColumnNames, in.SourceTableName, sourceReferenceColumnNames)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	targetColumnNames, err := utils.GetRelationshipColumnNames(in.TargetColumnNames, in.TargetTableName, targetReferenceColumnNames)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// self-referencing relationships need distinct column names
+	seen := make(map[string]bool)
+	for _, columnName := range append(append([]string{}, sourceColumnNames...), targetColumnNames...) {
+		if seen[strings.ToLower(columnName)] {
+			return status.Error(codes.InvalidArgument, fmt.Sprintf("column %s appears on both sides of the relationship", columnName))
+		}
+		seen[strings.ToLower(columnName)] = true
+	}
+
+	junctionSourceColumns := make([]Column, len(sourceColumns))
+	for i, sourceColumn := range sourceColumns {
+		junctionSourceColumns[i] = Column{Name: sourceColumnNames[i], Type: sourceColumn.Type}
+	}
+	junctionTargetColumns := make([]Column, len(targetColumns))
+	for i, targetColumn := range targetColumns {
+		junctionTargetColumns[i] = Column{Name: targetColumnNames[i], Type: targetColumn.Type}
+	}
+
+	if relationship.Name == "" {
+		relationship.Name = junctionTableName
+	}
+	relationshipExists, err := utils.CheckRelationshipExists(tenantDB.Db, relationship.Name)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check if relationship exists")
+	}
+	if relationshipExists {
+		return status.Error(codes.AlreadyExists, "relationship already exists")
+	}
+
+	sourceConstraintName := utils.GetConstraintName(utils.ForeignKeyPrefix, junctionTableName, sourceColumnNames)
+	targetConstraintName := utils.GetConstraintName(utils.ForeignKeyPrefix, junctionTableName, targetColumnNames)
+	relationship.JunctionTableName = junctionTableName
+	relationship.ColumnNames = sourceColumnNames
+	relationship.TargetColumnNames = targetColumnNames
+	relationship.ConstraintNames = []string{sourceConstraintName, targetConstraintName}
+
+	// get the pre-parsed template
+	createJunctionTableTemplate, err := s.templates.Get("create_junction_table")
+	if err != nil {
+		return status.Error(codes.Internal, "failed to create relationship")
+	}
+
+	// Execute the template and write the output to a string
+	var createJunctionTableSQL bytes.Buffer
+	err = createJunctionTableTemplate.Execute(&createJunctionTableSQL, struct {
+		TableName                  string
+		SourceColumns              []Column
+		TargetColumns              []Column
+		SourceColumnNames          []string
+		TargetColumnNames          []string
+		SourceConstraintName       string
+		TargetConstraintName       string
+		SourceTableName            string
+		TargetTableName            string
+		SourceReferenceColumnNames []string
+		TargetReferenceColumnNames []string
+		OnUpdate                   string
+		OnDelete                   string
+	}{
+		TableName:                  junctionTableName,
+		SourceColumns:              junctionSourceColumns,
+		TargetColumns:              junctionTargetColumns,
+		SourceColumnNames:          sourceColumnNames,
+		TargetColumnNames:          targetColumnNames,
+		SourceConstraintName:       sourceConstraintName,
+		TargetConstraintName:       targetConstraintName,
+		SourceTableName:            sourceTableName,
+		TargetTableName:            targetTableName,
+		SourceReferenceColumnNames: sourceReferenceColumnNames,
+		TargetReferenceColumnNames: targetReferenceColumnNames,
+		OnUpdate:                   utils.GetReferentialActionsFromEnum(in.OnUpdate),
+		OnDelete:                   utils.GetReferentialActionsFromEnum(in.OnDelete),
+	})
+	if err != nil {
+		return status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Create the junction table
+	_, err = tenantDB.Db.Exec(createJunctionTableSQL.String())
+	if err != nil {
+		log.Printf("failed to create junction table: %v", err)
+		return status.Error(codes.Internal, "failed to create junction table")
+	}
+
+	return nil
+}
+
+func (s *SchemaManagementService) ListRelationships(ctx context.Context, in *pb.ListRelationshipsRequest) (*pb.ListRelationshipsResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	relationships, err := utils.GetRelationships(tenantDB.Db, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list relationships")
+	}
+
+	return &pb.ListRelationshipsResponse{Relationships: relationships}, nil
+}
+
+func (s *SchemaManagementService) ListTrash(ctx context.Context, in *emptypb.Empty) (*pb.ListTrashResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trashEntries, err := utils.GetTrashEntries(tenantDB.Db)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list trash")
+	}
+
+	entries := make([]*pb.TrashEntry, len(trashEntries))
+	for i, trashEntry := range trashEntries {
+		entries[i] = trashEntry.Entry
+	}
+
+	return &pb.ListTrashResponse{Entries: entries}, nil
+}
+
+func (s *SchemaManagementService) RestoreFromTrash(ctx context.Context, in *pb.RestoreFromTrashRequest) (*pb.RestoreFromTrashResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trashEntries, err := s.getTrashEntries(ctx, in.Id, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(trashEntries) == 0 {
+		return nil, status.Error(codes.NotFound, "trash entry not found")
+	}
+	trashEntry := trashEntries[0]
+
+	var unrestored int
+	switch trashEntry.Entry.Kind {
+	case pb.TrashEntryKind_TABLE:
+		unrestored, err = s.restoreTable(tenantDB, trashEntry)
+	case pb.TrashEntryKind_COLUMN:
+		err = s.restoreColumn(tenantDB, trashEntry)
+	default:
+		return nil, status.Error(codes.Internal, "invalid trash entry kind")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// the entry is no longer in the trash
+	err = utils.DeleteTrashEntry(tenantDB.Db, trashEntry.Entry.Id)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to delete trash entry")
+	}
+
+	message := "restored from trash"
+	if unrestored > 0 {
+		message += fmt.Sprintf(", but %d foreign keys could not be added back", unrestored)
+	}
+
+	return &pb.RestoreFromTrashResponse{Message: message}, nil
+}
+
+// restoreTable renames the trashed table back and adds back the foreign keys
+// it gave up, returning how many of them could not be.
+func (s *SchemaManagementService) restoreTable(tenantDB *db.SchemaManagementServiceDB, trashEntry *utils.TrashEntryDetails) (int, error) {
+	// the original name must be free
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, trashEntry.Entry.TableName)
+	if err != nil {
+		return 0, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if tableExists {
+		return 0, status.Error(codes.AlreadyExists, "table already exists")
+	}
+
+	// get the pre-parsed template
+	restoreTableTemplate, err := s.templates.Get("restore_table")
+	if err != nil {
+		return 0, status.Error(codes.Internal, "failed to restore table")
+	}
+
+	// Execute the template and write the output to a string
+	var restoreTableSQL bytes.Buffer
+	err = restoreTableTemplate.Execute(&restoreTableSQL, struct {
+		TableName      string
+		TrashTableName string
+	}{
+		TableName:      trashEntry.Entry.TableName,
+		TrashTableName: trashEntry.Entry.TrashTableName,
+	})
+	if err != nil {
+		return 0, status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Rename the table back
+	_, err = tenantDB.Db.Exec(restoreTableSQL.String())
+	if err != nil {
+		log.Printf("failed to restore table: %v", err)
+		return 0, status.Error(codes.Internal, "failed to restore table")
+	}
+
+	// add back the foreign keys the table gave up, those whose referenced
+	// table is gone meanwhile cannot be
+	unrestored := 0
+	for _, restoreSQL := range trashEntry.RestoreSQL {
+		_, err = tenantDB.Db.Exec(restoreSQL)
+		if err != nil {
+			log.Printf("failed to add back a foreign key of %s: %v", trashEntry.Entry.TableName, err)
+			unrestored++
+		}
+	}
+
+	return unrestored, nil
+}
+
+func (s *SchemaManagementService) restoreColumn(tenantDB *db.SchemaManagementServiceDB, trashEntry *utils.TrashEntryDetails) error {
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, trashEntry.Entry.TableName)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return status.Error(codes.NotFound, "table not found")
+	}
+
+	columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, trashEntry.Entry.TableName, trashEntry.Entry.ColumnName)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to check if column exists")
+	}
+	if columnExists {
+		return status.Error(codes.AlreadyExists, "column already exists")
+	}
+
+	restoreColumnTemplate, err := s.templates.Get("restore_column")
+	if err != nil {
+		return status.Error(codes.Internal, "failed to restore column")
+	}
+
+	restoreColumnDataTemplate, err := s.templates.Get("restore_column_data")
+	if err != nil {
+		return status.Error(codes.Internal, "failed to restore column")
+	}
+
+	dropColumnTemplate, err := s.templates.Get("drop_column")
+	if err != nil {
+		return status.Error(codes.Internal, "failed to restore column")
+	}
+
+	// Execute the templates and write the output to strings
+	payload := struct {
+		TableName        string
+		ColumnName       string
+		ColumnDefinition string
+		TrashTableName   string
+		KeyColumns       []string
+	}{
+		TableName:        trashEntry.Entry.TableName,
+		ColumnName:       trashEntry.Entry.ColumnName,
+		ColumnDefinition: trashEntry.ColumnDefinition,
+		TrashTableName:   trashEntry.Entry.TrashTableName,
+		KeyColumns:       trashEntry.KeyColumns,
+	}
+
+	var restoreColumnSQL bytes.Buffer
+	err = restoreColumnTemplate.Execute(&restoreColumnSQL, payload)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to execute template")
+	}
+
+	var restoreColumnDataSQL bytes.Buffer
+	err = restoreColumnDataTemplate.Execute(&restoreColumnDataSQL, payload)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to execute template")
+	}
+
+	var dropColumnSQL bytes.Buffer
+	err = dropColumnTemplate.Execute(&dropColumnSQL, payload)
+	if err != nil {
+		return status.Error(codes.Internal, "failed to execute template")
+	}
+
+	// Recreate the column
+	_, err = tenantDB.Db.Exec(restoreColumnSQL.String())
+	if err != nil {
+		log.Printf("failed to restore column: %v", err)
+		return status.Error(codes.Internal, "failed to restore column")
+	}
+
+	// Copy the data back, dropping the recreated column again on failure so
+	// the restore can be retried
+	_, err = tenantDB.Db.Exec(restoreColumnDataSQL.String())
+	if err != nil {
+		log.Printf("failed to restore column data: %v", err)
+		_, dropErr := tenantDB.Db.Exec(dropColumnSQL.String())
+		if dropErr != nil {
+			log.Printf("failed to drop the recreated column %s.%s: %v", trashEntry.Entry.TableName, trashEntry.Entry.ColumnName, dropErr)
+		}
+		return status.Error(codes.Internal, "failed to restore column data")
+	}
+
+	// the data is back, a side table left behind is only wasted space
+	s.dropTrashTable(tenantDB, trashEntry.Entry.TrashTableName)
+
+	return nil
+}
+
+func (s *SchemaManagementService) PurgeTrash(ctx context.Context, in *pb.PurgeTrashRequest) (*pb.PurgeTrashResponse, error) {
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trashEntries, err := s.getTrashEntries(ctx, in.Id, in.All)
+	if err != nil {
+		return nil, err
+	}
+	if !in.All && len(trashEntries) == 0 {
+		return nil, status.Error(codes.NotFound, "trash entry not found")
+	}
+
+	for _, trashEntry := range trashEntries {
+		err := s.purgeTrashEntry(tenantDB, trashEntry)
+		if err != nil {
+			log.Printf("failed to purge trash entry %d: %v", trashEntry.Entry.Id, err)
+			return nil, status.Error(codes.Internal, "failed to purge trash")
+		}
+	}
+
+	return &pb.PurgeTrashResponse{Message: fmt.Sprintf("%d trash entries purged", len(trashEntries))}, nil
+}
+
+// purgeTrashEntry permanently drops the trashed table or column data.
+func (s *SchemaManagementService) purgeTrashEntry(tenantDB *db.SchemaManagementServiceDB, trashEntry *utils.TrashEntryDetails) error {
+	// get the pre-parsed template
+	purgeTrashTemplate, err := s.templates.Get("purge_trash")
+	if err != nil {
+		return err
+	}
+
+	// Execute the template and write the output to a string
+	var purgeTrashSQL bytes.Buffer
+	err = purgeTrashTemplate.Execute(&purgeTrashSQL, struct {
+		TrashTableName string
+	}{
+		TrashTableName: trashEntry.Entry.TrashTableName,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Drop the trash table
+	_, err = tenantDB.Db.Exec(purgeTrashSQL.String())
+	if err != nil {
+		return err
+	}
+
+	// the bookkeeping was kept for a restore, unless the name has been reused
+	switch trashEntry.Entry.Kind {
+	case pb.TrashEntryKind_TABLE:
+		tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, trashEntry.Entry.TableName)
+		if err != nil {
+			return err
+		}
+		if !tableExists {
+			s.deleteTableBookkeeping(tenantDB, trashEntry.Entry.TableName)
+		}
+	case pb.TrashEntryKind_COLUMN:
+		columnExists, err := utils.CheckColumnExists(tenantDB.Db, tenantDB.Name, trashEntry.Entry.TableName, trashEntry.Entry.ColumnName)
+		if err != nil {
+			return err
+		}
+		if !columnExists {
+			err = utils.DeleteColumnMetadata(tenantDB.Db, trashEntry.Entry.TableName, trashEntry.Entry.ColumnName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return utils.DeleteTrashEntry(tenantDB.Db, trashEntry.Entry.Id)
+}
+
+// getSystemColumns returns the columns of the table managed by the service.
+func (s *SchemaManagementService) getSystemColumns(tenantDB *db.SchemaManagementServiceDB, tableName string) (utils.SystemColumns, error) {
+	systemColumns, err := s.systemConfig.GetSystemColumns(tenantDB.Db, tenantDB.Name, tableName)
+	if err != nil {
+		log.Printf("failed to get the system columns of %s: %v", tableName, err)
+		return nil, status.Error(codes.Internal, "failed to get system columns")
+	}
+	return systemColumns, nil
+}
+
+// deleteTableBookkeeping forgets the relationships and column metadata of a
+// dropped table. Failures are only logged, the table is already gone.
+func (s *SchemaManagementService) deleteTableBookkeeping(tenantDB *db.SchemaManagementServiceDB, tableName string) {
+	err := utils.DeleteTableRelationships(tenantDB.Db, tableName)
+	if err != nil {
+		log.Printf("failed to delete the relationships of %s: %v", tableName, err)
+	}
+
+	err = utils.DeleteTableColumnMetadata(tenantDB.Db, tableName)
+	if err != nil {
+		log.Printf("failed to delete the column metadata of %s: %v", tableName, err)
+	}
+}
+
+// deleteTrashEntry forgets a trash entry whose table or column could not be
+// moved. Failures are only logged, the caller is already failing.
+func (s *SchemaManagementService) deleteTrashEntry(tenantDB *db.SchemaManagementServiceDB, id uint64) {
+	err := utils.DeleteTrashEntry(tenantDB.Db, id)
+	if err != nil {
+		log.Printf("failed to delete trash entry %d: %v", id, err)
+	}
+}
+
+// dropTrashTable drops a trash table on a best effort basis.
+func (s *SchemaManagementService) dropTrashTable(tenantDB *db.SchemaManagementServiceDB, trashTableName string) {
+	purgeTrashTemplate, err := s.templates.Get("purge_trash")
+	if err != nil {
+		log.Printf("failed to remove trash table %s: %v", trashTableName, err)
+		return
+	}
+
+	var purgeTrashSQL bytes.Buffer
+	err = purgeTrashTemplate.Execute(&purgeTrashSQL, struct {
+		TrashTableName string
+	}{
+		TrashTableName: trashTableName,
+	})
+	if err == nil {
+		_, err = tenantDB.Db.Exec(purgeTrashSQL.String())
+	}
+	if err != nil {
+		log.Printf("failed to remove trash table %s: %v", trashTableName, err)
+	}
+}
+
+// purgeExpiredTrash periodically purges the trash entries older than the
+// retention, in the default database and in every project database. It
+// returns once the context is done and the purge under way, if any, stopped.
+func (s *SchemaManagementService) purgeExpiredTrash(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		s.purgeExpiredTrashEntries(ctx, s.schemaManagementServiceDB, retention)
+
+		projectIds, err := utils.GetTenantProjectIds(s.schemaManagementServiceDB.Db)
+		if err != nil {
+			log.Printf("failed to list the projects: %v", err)
+			continue
+		}
+
+		// purge the projects side by side, so an unreachable database only
+		// holds up its own purge
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, trashPurgeConcurrency)
+		for _, projectId := range projectIds {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(projectId string) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				// the projects left are skipped on shutdown
+				if ctx.Err() != nil {
+					return
+				}
+				tenantDB, err := s.tenants.Get(projectId)
+				if err != nil {
+					log.Printf("failed to open the database of project %s: %v", projectId, err)
+					return
+				}
+				s.purgeExpiredTrashEntries(ctx, tenantDB, retention)
+			}(projectId)
+		}
+		wg.Wait()
+	}
+}
+
+func (s *SchemaManagementService) purgeExpiredTrashEntries(ctx context.Context, tenantDB *db.SchemaManagementServiceDB, retention time.Duration) {
+	trashEntries, err := utils.GetExpiredTrashEntries(tenantDB.Db, retention)
+	if err != nil {
+		log.Printf("failed to list expired trash entries of %s: %v", tenantDB.Name, err)
+		return
+	}
+
+	for _, trashEntry := range trashEntries {
+		if ctx.Err() != nil {
+			return
+		}
+		err = s.purgeTrashEntry(tenantDB, trashEntry)
+		if err != nil {
+			log.Printf("failed to purge trash entry %d of %s: %v", trashEntry.Entry.Id, tenantDB.Name, err)
+			continue
+		}
+		log.Printf("purged expired trash entry %s of %s", trashEntry.Entry.TrashTableName, tenantDB.Name)
+	}
+}
+
+// checkHealth periodically pings the database and reports the result to the
+// health service, for the server as a whole and for the SchemaService. It
+// returns once the context is done.
+func (s *SchemaManagementService) checkHealth(ctx context.Context, healthServer *health.Server, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastStatus := healthpb.HealthCheckResponse_UNKNOWN
+	for {
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := s.schemaManagementServiceDB.Db.PingContext(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+
+		servingStatus := healthpb.HealthCheckResponse_SERVING
+		if err != nil {
+			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+		}
+		if servingStatus != lastStatus {
+			if err != nil {
+				log.Printf("failed to ping the database: %v", err)
+			}
+			log.Printf("health status is now %s", servingStatus)
+			lastStatus = servingStatus
+		}
+		healthServer.SetServingStatus("", servingStatus)
+		healthServer.SetServingStatus(pb.SchemaService_ServiceDesc.ServiceName, servingStatus)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *SchemaManagementService) CloneTable(ctx context.Context, in *pb.CloneTableRequest) (*pb.CloneTableResponse, error) {
+	if !s.config.Features.SchemaCloning {
+		return nil, status.Error(codes.Unimplemented, "schema cloning is disabled")
+	}
+
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the internal bookkeeping tables are managed by the service
+	if utils.IsSystemTable(in.TableName) || utils.IsSystemTable(in.NewTableName) {
+		return nil, status.Error(codes.PermissionDenied, "system tables cannot be cloned")
+	}
+	if !utils.IsValidIdentifier(in.NewTableName) {
+		return nil, status.Error(codes.InvalidArgument, "invalid table name")
+	}
+
+	batchSize, err := utils.ResolveCloneBatchSize(in.BatchSize)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Check if the table exists
+	tableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.TableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if !tableExists {
+		return nil, status.Error(codes.NotFound, "table not found")
+	}
+
+	// the clone must not shadow an existing table
+	newTableExists, err := utils.CheckTableExists(tenantDB.Db, tenantDB.Name, in.NewTableName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if table exists")
+	}
+	if newTableExists {
+		return nil, status.Error(codes.AlreadyExists, "table already exists")
+	}
+
+	clones := []tableClone{{TableName: in.TableName, TargetTableName: in.NewTableName}}
+	err = s.cloneTables(tenantDB, tenantDB.Name, clones, in.CopyData, batchSize)
+	if err != nil {
+		log.Printf("failed to clone table %s: %v", in.TableName, err)
+		s.dropClonedTables(tenantDB, tenantDB.Name, clones)
+		return nil, status.Error(codes.Internal, "failed to clone table")
+	}
+
+	return &pb.CloneTableResponse{Message: "table cloned"}, nil
+}
+
+// CloneSchema clones every user table of the project database into the
+// database of another project on the same server. The policy authorizes the
+// call in both projects, see resolveResources.
+func (s *SchemaManagementService) CloneSchema(ctx context.Context, in *pb.CloneSchemaRequest) (*pb.CloneSchemaResponse, error) {
+	if !s.config.Features.SchemaCloning {
+		return nil, status.Error(codes.Unimplemented, "schema cloning is disabled")
+	}
+
+	// route the request to the project database
+	tenantDB, err := s.getTenantDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !utils.IsValidProjectId(in.TargetProjectId) {
+		return nil, status.Error(codes.InvalidArgument, "invalid target project id")
+	}
+	targetDB, err := s.tenants.Get(in.TargetProjectId)
+	if errors.Is(err, db.ErrTenantNotFound) {
+		return nil, status.Error(codes.NotFound, "target project not found")
+	}
+	if err != nil {
+		log.Printf("failed to open the database of project %s: %v", in.TargetProjectId, err)
+		return nil, status.Error(codes.Internal, "failed to open the target project database")
+	}
+	if targetDB.Name == tenantDB.Name {
+		return nil, status.Error(codes.InvalidArgument, "a schema cannot be cloned into itself")
+	}
+
+	batchSize, err := utils.ResolveCloneBatchSize(in.BatchSize)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// clone the tables the policy authorized, if it listed them
+	tableNames, ok := ctx.Value(tableNamesKey{}).([]string)
+	if !ok {
+		tableNames, err = utils.GetTableNames(tenantDB.Db, tenantDB.Name)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to list tables")
+		}
+	}
+	if len(tableNames) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "schema has no tables")
+	}
+
+	// the clones keep their names, none of them may exist in the target
+	clones := make([]tableClone, len(tableNames))
+	for i, tableName := range tableNames {
+		tableExists, err := utils.CheckTableExists(tenantDB.Db, targetDB.Name, tableName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to check if table exists")
+		}
+		if tableExists {
+			return nil, status.Error(codes.AlreadyExists, fmt.Sprintf("table %s already exists in the target database", tableName))
+		}
+		clones[i] = tableClone{TableName: tableName, TargetTableName: tableName}
+	}
+
+	err = s.cloneTables(tenantDB, targetDB.Name, clones, in.CopyData, batchSize)
+	if err != nil {
+		log.Printf("failed to clone schema %s into %s: %v", tenantDB.Name, targetDB.Name, err)
+		s.dropClonedTables(tenantDB, targetDB.Name, clones)
+		return nil, status.Error(codes.Internal, "failed to clone schema")
+	}
+
+	// the relationships only involve cloned tables, they hold in the target too
+	err = utils.CopyRelationships(tenantDB.Db, tenantDB.Name, targetDB.Name)
+	if err != nil {
+		log.Printf("failed to copy the relationships of %s into %s: %v", tenantDB.Name, targetDB.Name, err)
+		s.dropClonedTables(tenantDB, targetDB.Name, clones)
+		return nil, status.Error(codes.Internal, "failed to copy relationships")
+	}
+
+	return &pb.CloneSchemaResponse{
+		Message:    fmt.Sprintf("%d tables cloned", len(tableNames)),
+		TableNames: tableNames,
+	}, nil
+}
+
+// tableClone pairs a table with the name of its clone.
+type tableClone struct {
+	TableName       string
+	TargetTableName string
+}
+
+// cloneTables creates the clones of the tables in the target database, which
+// may be the source one, and optionally copies their rows. The foreign keys
+// are added last, once the data is in place, and point at the clones when the
+// referenced table was cloned along.
+func (s *SchemaManagementService) cloneTables(tenantDB *db.SchemaManagementServiceDB, targetDatabaseName string, clones []tableClone, copyData bool, batchSize int) error {
+	// get the pre-parsed templates
+	cloneTableTemplate, err := s.templates.Get("clone_table")
+	if err != nil {
+		return err
+	}
+	copyTableDataTemplate, err := s.templates.Get("copy_table_data")
+	if err != nil {
+		return err
+	}
+	addForeignKeyTemplate, err := s.templates.Get("add_foreign_key_existing_column")
+	if err != nil {
+		return err
+	}
+
+	// map the cloned tables to their clones to re-point the foreign keys
+	cloneNames := make(map[string]string)
+	for _, clone := range clones {
+		cloneNames[clone.TableName] = clone.TargetTableName
+	}
+
+	// create the tables, CREATE TABLE ... LIKE leaves out the foreign keys
+	for _, clone := range clones {
+		var cloneTableSQL bytes.Buffer
+		err = cloneTableTemplate.Execute(&cloneTableSQL, struct {
+			DatabaseName       string
+			TableName          string
+			TargetDatabaseName string
+			TargetTableName    string
+		}{
+			DatabaseName:       tenantDB.Name,
+			TableName:          clone.TableName,
+			TargetDatabaseName: targetDatabaseName,
+			TargetTableName:    clone.TargetTableName,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tenantDB.Db.Exec(cloneTableSQL.String())
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", clone.TargetTableName, err)
+		}
+
+		err = utils.CopyColumnMetadata(tenantDB.Db, tenantDB.Name, clone.TableName, targetDatabaseName, clone.TargetTableName)
+		if err != nil {
+			return fmt.Errorf("failed to copy the column metadata of %s: %w", clone.TableName, err)
+		}
+	}
+
+	// copy the rows before any foreign key constrains their order, in key
+	// ranges, each batch seeking past the last one. The copy is not a
+	// snapshot: a batch sees the rows committed when it runs, so rows written
+	// meanwhile to the ranges already copied are left out, but no row is
+	// copied twice.
+	if copyData {
+		for _, clone := range clones {
+			columnNames, err := utils.GetInsertableColumnNames(tenantDB.Db, tenantDB.Name, clone.TableName)
+			if err != nil {
+				return err
+			}
+			// batches need a key, tables without one are copied at once
+			primaryKeyColumns, err := utils.GetPrimaryKeyColumns(tenantDB.Db, tenantDB.Name, clone.TableName)
+			if err != nil {
+				return err
+			}
+
+			var after []any
+			for {
+				var through []any
+				if len(primaryKeyColumns) > 0 {
+					through, err = utils.GetCloneBatchEnd(tenantDB.Db, tenantDB.Name, clone.TableName, primaryKeyColumns, after, batchSize)
+					if err != nil {
+						return fmt.Errorf("failed to get the next batch of %s: %w", clone.TableName, err)
+					}
+				}
+
+				var copyTableDataSQL bytes.Buffer
+				err = copyTableDataTemplate.Execute(&copyTableDataSQL, struct {
+					DatabaseName       string
+					TableName          string
+					TargetDatabaseName string
+					TargetTableName    string
+					ColumnNames        []string
+					KeyColumns         []string
+					KeyPlaceholders    string
+					After              bool
+					Through            bool
+				}{
+					DatabaseName:       tenantDB.Name,
+					TableName:          clone.TableName,
+					TargetDatabaseName: targetDatabaseName,
+					TargetTableName:    clone.TargetTableName,
+					ColumnNames:        columnNames,
+					KeyColumns:         primaryKeyColumns,
+					KeyPlaceholders:    utils.KeyPlaceholders(len(primaryKeyColumns)),
+					After:              after != nil,
+					Through:            through != nil,
+				})
+				if err != nil {
+					return err
+				}
+
+				args := append(append([]any{}, after...), through...)
+				_, err = tenantDB.Db.Exec(copyTableDataSQL.String(), args...)
+				if err != nil {
+					return fmt.Errorf("failed to copy the rows of %s: %w", clone.TableName, err)
+				}
+
+				// the last batch runs to the end of the table
+				if through == nil {
+					break
+				}
+				after = through
+			}
+		}
+	}
+
+	// add the foreign keys, pointing at the clones where there are some
+	for _, clone := range clones {
+		foreignKeys, err := utils.GetForeignKeys(tenantDB.Db, tenantDB.Name, clone.TableName)
+		if err != nil {
+			return err
+		}
+		referenceSchemas, err := utils.GetForeignKeyReferenceSchemas(tenantDB.Db, tenantDB.Name, clone.TableName)
+		if err != nil {
+			return err
+		}
+
+		for _, foreignKey := range foreignKeys {
+			referenceSchema := referenceSchemas[foreignKey.ConstraintName]
+			referenceTableName := foreignKey.ReferenceTableName
+			if cloneName, ok := cloneNames[referenceTableName]; ok && referenceSchema == tenantDB.Name {
+				referenceSchema = targetDatabaseName
+				referenceTableName = cloneName
+			}
+
+			// constraint names are unique per schema, clones in place need new ones
+			constraintName := foreignKey.ConstraintName
+			if targetDatabaseName == tenantDB.Name {
+				constraintName = utils.GetConstraintName(utils.ForeignKeyPrefix, clone.TargetTableName, foreignKey.ColumnNames)
+			}
+
+			var addForeignKeySQL bytes.Buffer
+			err = addForeignKeyTemplate.Execute(&addForeignKeySQL, struct {
+				TableName            string
+				ConstraintName       string
+				IndexName            string
+				ColumnNames          []string
+				ReferenceTableName   string
+				ReferenceColumnNames []string
+				CreateIndex          bool
+				OnUpdate             string
+				OnDelete             string
+			}{
+				TableName:            fmt.Sprintf("`%s`.`%s`", targetDatabaseName, clone.TargetTableName),
+				ConstraintName:       constraintName,
+				ColumnNames:          foreignKey.ColumnNames,
+				ReferenceTableName:   fmt.Sprintf("`%s`.`%s`", referenceSchema, referenceTableName),
+				ReferenceColumnNames: foreignKey.ReferenceColumnNames,
+				OnUpdate:             utils.GetReferentialActionsFromEnum(foreignKey.OnUpdate),
+				OnDelete:             utils.GetReferentialActionsFromEnum(foreignKey.OnDelete),
+			})
+			if err != nil {
+				return err
+			}
+
+			_, err = tenantDB.Db.Exec(addForeignKeySQL.String())
+			if err != nil {
+				return fmt.Errorf("failed to add foreign key %s to %s: %w", constraintName, clone.TargetTableName, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dropClonedTables removes the clones of a failed clone, along with their
+// column metadata. Failures are only logged, the original error is reported
+// instead.
+func (s *SchemaManagementService) dropClonedTables(tenantDB *db.SchemaManagementServiceDB, targetDatabaseName string, clones []tableClone) {
+	targetTableNames := make([]string, len(clones))
+	for i, clone := range clones {
+		targetTableNames[i] = clone.TargetTableName
+	}
+
+	err := utils.DropTables(tenantDB.Db, targetDatabaseName, targetTableNames)
+	if err != nil {
+		log.Printf("failed to drop the clones in %s: %v", targetDatabaseName, err)
+	}
+
+	for _, targetTableName := range targetTableNames {
+		err = utils.DeleteClonedColumnMetadata(tenantDB.Db, targetDatabaseName, targetTableName)
+		if err != nil {
+			log.Printf("failed to delete the column metadata of %s: %v", targetTableName, err)
+		}
+	}
+}
+
+func (s *SchemaManagementService) CreateDatabase(ctx context.Context, in *pb.CreateDatabaseRequest) (*pb.CreateDatabaseResponse, error) {
+	if !s.config.Features.DatabaseProvisioning {
+		return nil, status.Error(codes.Unimplemented, "database provisioning is disabled")
+	}
+
+	if !utils.IsValidProjectId(in.ProjectId) {
+		return nil, status.Error(codes.InvalidArgument, "invalid project id")
+	}
+
+	// the project databases are provisioned from the default database
+	controlDB := s.schemaManagementServiceDB
+
+	// a project is routed to a single database
+	_, err := utils.GetTenantDatabaseName(controlDB.Db, in.ProjectId)
+	if err == nil {
+		return nil, status.Error(codes.AlreadyExists, "project already has a database")
+	}
+	if !errors.Is(err, db.ErrTenantNotFound) {
+		return nil, status.Error(codes.Internal, "failed to check if project exists")
+	}
+
+	databaseName, err := utils.GetProjectDatabaseName(in.ProjectId, in.DatabaseName)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	databaseExists, err := utils.CheckDatabaseExists(controlDB.Db, databaseName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check if database exists")
+	}
+	if databaseExists {
+		return nil, status.Error(codes.AlreadyExists, "database already exists")
+	}
+
+	// a retried drop would take the new database or user with it
+	dropPending, err := utils.CheckTenantDropPending(controlDB.Db, in.ProjectId, databaseName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to check for a pending drop")
+	}
+	if dropPending {
+		return nil, status.Error(codes.FailedPrecondition, "the previous database of the project is still being dropped, retry DropDatabase first")
+	}
+
+	// check the charset and collation against what the server supports
+	charset, collation := in.Charset, in.Collation
+	if charset != "" || collation != "" {
+		charset, collation, err = utils.ResolveCharsetAndCollation(controlDB.Db, charset, collation)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+
+	// the user is named after the database, an existing account is never
+	// taken over or dropped
+	username := utils.GetProjectUsername(databaseName)
+	userExists, err := utils.CheckProjectUserExists(controlDB.Db, username, s.config.ProjectUserHost)
+	if err != nil {
+		log.Printf("failed to check if user %s exists: %v", username, err)
+		return nil, status.Error(codes.Internal, "failed to check if database user exists")
+	}
+	if userExists {
+		return nil, status.Error(codes.AlreadyExists, "database user already exists")
+	}
+
+	password, err := utils.GeneratePassword()
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to generate password")
+	}
+
+	err = utils.CreateProjectDatabase(controlDB.Db, databaseName, charset, collation)
+	if err != nil {
+		log.Printf("failed to create database %s: %v", databaseName, err)
+		return nil, status.Error(codes.Internal, "failed to create database")
+	}
+
+	err = utils.CreateProjectUser(controlDB.Db, username, s.config.ProjectUserHost, password, databaseName)
+	if err != nil {
+		log.Printf("failed to create the user of database %s: %v", databaseName, err)
+		// no user is left behind by a failed creation
+		s.dropProjectDatabase(databaseName, "")
+		return nil, status.Error(codes.Internal, "failed to create database user")
+	}
+
+	err = utils.AddTenant(controlDB.Db, in.ProjectId, databaseName, username)
+	if err != nil {
+		log.Printf("failed to register database %s: %v", databaseName, err)
+		s.dropProjectDatabase(databaseName, username)
+		return nil, status.Error(codes.Internal, "failed to register database")
+	}
+
+	// open the database now so its bookkeeping tables exist before first use
+	_, err = s.tenants.Get(in.ProjectId)
+	if err != nil {
+		log.Printf("failed to open database %s: %v", databaseName, err)
+	}
+
+	databases, err := utils.GetTenantDatabases(controlDB.Db, in.ProjectId)
+	if err != nil || len(databases) == 0 {
+		return nil, status.Error(codes.Internal, "failed to get database")
+	}
+
+	return &pb.CreateDatabaseResponse{
+		Message:  "database created",
+		Database: databases[0],
+		Password: password,
+	}, nil
+}
+
+// dropProjectDatabase undoes a partially provisioned project database, and
+// drops the user when one was created for it. Failures are only logged, the
+// original error is reported instead.
+func (s *SchemaManagementService) dropProjectDatabase(databaseName, username string) {
+	if username != "" {
+		err := utils.DropProjectUser(s.schemaManagementServiceDB.Db, username, s.config.ProjectUserHost)
+		if err != nil {
+			log.Printf("failed to drop the user of database %s: %v", databaseName, err)
+		}
+	}
+
+	err := utils.DropProjectDatabase(s.schemaManagementServiceDB.Db, databaseName)
+	if err != nil {
+		log.Printf("failed to drop database %s: %v", databaseName, err)
+	}
+}
+
+func (s *SchemaManagementService) ListDatabases(ctx context.Context, in *emptypb.Empty) (*pb.ListDatabasesResponse, error) {
+	if !s.config.Features.DatabaseProvisioning {
+		return nil, status.Error(codes.Unimplemented, "database provisioning is disabled")
+	}
+
+	databases, err := utils.GetTenantDatabases(s.schemaManagementServiceDB.Db, "")
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to list databases")
+	}
+
+	return &pb.ListDatabasesResponse{Databases: databases}, nil
+}
+
+// DropDatabase drops the project database and its user. The first call only
+// returns a confirmation token, the database is dropped when the call is
+// repeated with it.
+func (s *SchemaManagementService) DropDatabase(ctx context.Context, in *pb.DropDatabaseRequest) (*pb.DropDatabaseResponse, error) {
+	if !s.config.Features.DatabaseProvisioning {
+		return nil, status.Error(codes.Unimplemented, "database provisioning is disabled")
+	}
+
+	if !utils.IsValidProjectId(in.ProjectId) {
+		return nil, status.Error(codes.InvalidArgument, "invalid project id")
+	}
+
+	databases, err := utils.GetTenantDatabases(s.schemaManagementServiceDB.Db, in.ProjectId)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get database")
+	}
+	if len(databases) == 0 {
+		// a drop that failed partway was confirmed already, finish it
+		database, err := utils.GetTenantDrop(s.schemaManagementServiceDB.Db, in.ProjectId)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to get database")
+		}
+		if database == nil {
+			return nil, status.Error(codes.NotFound, "project not found")
+		}
+		return s.finishDropDatabase(database)
+	}
+	database := databases[0]
+
+	if in.ConfirmationToken == "" {
+		token, err := s.confirmations.Issue(in.ProjectId)
+		if err != nil {
+			return nil, status.Error(codes.Internal, "failed to issue confirmation token")
+		}
+		return &pb.DropDatabaseResponse{
+			Message:           fmt.Sprintf("repeat the call with the confirmation token within %s to drop database %s", dropDatabaseConfirmationTTL, database.Name),
+			ConfirmationToken: token,
+		}, nil
+	}
+	if !s.confirmations.Consume(in.ProjectId, in.ConfirmationToken) {
+		return nil, status.Error(codes.FailedPrecondition, "invalid or expired confirmation token")
+	}
+
+	// stop routing requests to the database before anything is dropped
+	err = utils.StartTenantDrop(s.schemaManagementServiceDB.Db, in.ProjectId)
+	if err != nil {
+		log.Printf("failed to unregister database %s: %v", database.Name, err)
+		return nil, status.Error(codes.Internal, "failed to unregister database")
+	}
+
+	return s.finishDropDatabase(database)
+}
+
+// finishDropDatabase closes the pool of an unregistered project database and
+// drops the database and its user. Every step can be repeated, a failed drop
+// is finished by calling DropDatabase again.
+func (s *SchemaManagementService) finishDropDatabase(database *pb.Database) (*pb.DropDatabaseResponse, error) {
+	err := s.tenants.Remove(database.ProjectId)
+	if err != nil {
+		log.Printf("failed to close database %s: %v", database.Name, err)
+	}
+
+	err = utils.DropProjectDatabase(s.schemaManagementServiceDB.Db, database.Name)
+	if err != nil {
+		log.Printf("failed to drop database %s: %v", database.Name, err)
+		return nil, status.Error(codes.Internal, "failed to drop database, retry to finish")
+	}
+
+	err = utils.DropProjectUser(s.schemaManagementServiceDB.Db, database.Username, s.config.ProjectUserHost)
+	if err != nil {
+		log.Printf("failed to drop the user of database %s: %v", database.Name, err)
+		return nil, status.Error(codes.Internal, "failed to drop database user, retry to finish")
+	}
+
+	err = utils.FinishTenantDrop(s.schemaManagementServiceDB.Db, database.ProjectId)
+	if err != nil {
+		log.Printf("failed to record the drop of database %s: %v", database.Name, err)
+		return nil, status.Error(codes.Internal, "failed to record the drop, retry to finish")
+	}
+
+	return &pb.DropDatabaseResponse{Message: "database dropped"}, nil
+}
+
+func main() {
+	// load the configuration from the flags, the environment and the YAML file
+	serviceConfig, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalf("failed to load the configuration: %v", err)
 	}
 	// Create a new schemaManagementServiceDB
-	schemaManagementServiceDB, err := db.NewSchemaManagementServiceDB()
+	schemaManagementServiceDB, err := db.NewSchemaManagementServiceDB(&serviceConfig.MySQL)
 	if err != nil {
 		log.Fatalf("failed to create a new SchemaManagementServiceDB: %v", err)
 	}
@@ -727,20 +3813,194 @@ func main() {
 		log.Fatalf("failed to ping the database: %v", err)
 	}
 
+	// create the bookkeeping tables of the default database
+	err = utils.CreateBookkeepingTables(schemaManagementServiceDB.Db)
+	if err != nil {
+		log.Fatalf("failed to create the bookkeeping tables: %v", err)
+	}
+
+	// create the table mapping the projects to their databases
+	err = utils.CreateTenantsTable(schemaManagementServiceDB.Db)
+	if err != nil {
+		log.Fatalf("failed to create the tenants table: %v", err)
+	}
+
+	// the project databases are opened on first use
+	tenants := db.NewTenantPool(
+		&serviceConfig.MySQL,
+		func(projectId string) (string, error) {
+			return utils.GetTenantDatabaseName(schemaManagementServiceDB.Db, projectId)
+		},
+		func(tenantDB *db.SchemaManagementServiceDB) error {
+			return utils.CreateBookkeepingTables(tenantDB.Db)
+		},
+	)
+
 	// Start the server
-	ls, err := net.Listen("tcp", ":8084")
+	ls, err := net.Listen("tcp", serviceConfig.ListenAddress)
 	if err != nil {
 		log.Fatalf("failed to listen: %v", err)
 	}
 
-	s := grpc.NewServer()
-	pb.RegisterSchemaServiceServer(s, &SchemaManagementService{
+	// parse the embedded templates and their overrides
+	templateRegistry, err := templates.NewRegistry(serviceConfig.TemplatesOverrideDir)
+	if err != nil {
+		log.Fatalf("failed to parse the templates: %v", err)
+	}
+
+	// load the system columns configuration
+	systemConfig, err := utils.NewSystemConfig(&serviceConfig.SystemColumns)
+	if err != nil {
+		log.Fatalf("failed to load the system configuration: %v", err)
+	}
+
+	schemaManagementService := &SchemaManagementService{
 		schemaManagementServiceDB: schemaManagementServiceDB,
+		tenants:                   tenants,
+		systemConfig:              systemConfig,
+		config:                    serviceConfig,
+		confirmations:             utils.NewConfirmationTokens(dropDatabaseConfirmationTTL),
+		templates:                 templateRegistry,
+	}
+
+	// the background loops stop on shutdown, before the databases are closed
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	var background sync.WaitGroup
+	runInBackground := func(loop func()) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			loop()
+		}()
+	}
+
+	// purge the trash entries older than the retention, 0 keeps them forever
+	if serviceConfig.Trash.Retention > 0 {
+		runInBackground(func() {
+			schemaManagementService.purgeExpiredTrash(ctx, serviceConfig.Trash.Retention, serviceConfig.Trash.PurgeInterval)
+		})
+	}
+
+	// serve TLS when a certificate is configured, mutual TLS with a client CA
+	var serverOptions []grpc.ServerOption
+	if serviceConfig.TLS.CertFile != "" {
+		tlsReloader, err := utils.NewTLSReloader(
+			serviceConfig.TLS.CertFile,
+			serviceConfig.TLS.KeyFile,
+			serviceConfig.TLS.ClientCAFile,
+		)
+		if err != nil {
+			log.Fatalf("failed to load the TLS certificates: %v", err)
+		}
+		runInBackground(func() {
+			tlsReloader.Watch(ctx, serviceConfig.TLS.ReloadInterval)
+		})
+
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsReloader.TLSConfig())))
+	} else {
+		log.Printf("TLS is disabled, serving plaintext")
+	}
+
+	var unaryInterceptors []grpc.UnaryServerInterceptor
+	var streamInterceptors []grpc.StreamServerInterceptor
+	var authorizer *auth.Authorizer
+
+	// authenticate every call, unless disabled for local development
+	if serviceConfig.Auth.Disabled {
+		log.Printf("authentication is disabled")
+	} else {
+		authenticator, err := auth.NewAuthenticator(auth.Config{
+			APIKeysFile: serviceConfig.Auth.APIKeysFile,
+			JWKSFile:    serviceConfig.Auth.JWKSFile,
+			Issuer:      serviceConfig.Auth.JWTIssuer,
+			Audience:    serviceConfig.Auth.JWTAudience,
+		})
+		if err != nil {
+			log.Fatalf("failed to load the credentials: %v", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, authenticator.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authenticator.StreamServerInterceptor())
+
+		// authorize the authenticated calls against the policy file
+		authorizer, err = auth.NewAuthorizer(serviceConfig.Auth.PolicyFile, schemaManagementService.resolveResources)
+		if err != nil {
+			log.Fatalf("failed to load the policy: %v", err)
+		}
+		runInBackground(func() {
+			authorizer.WatchPolicy(ctx, serviceConfig.Auth.PolicyReloadInterval)
+		})
+	}
+
+	// reject malformed names before the policy lookups or the handlers use them
+	unaryInterceptors = append(unaryInterceptors, validateIdentifiers)
+	if authorizer != nil {
+		unaryInterceptors = append(unaryInterceptors, authorizer.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, authorizer.StreamServerInterceptor())
+	}
+	serverOptions = append(
+		serverOptions,
+		grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...),
+	)
+
+	s := grpc.NewServer(serverOptions...)
+	pb.RegisterSchemaServiceServer(s, schemaManagementService)
+
+	// report the database health to grpc.health.v1 probes
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	runInBackground(func() {
+		schemaManagementService.checkHealth(ctx, healthServer, serviceConfig.HealthCheckInterval)
 	})
 
+	// let grpcurl and similar tools discover the services
+	if serviceConfig.Features.Reflection {
+		reflection.Register(s)
+	}
+
+	// drain the in-flight calls on shutdown, up to the deadline
+	shutdownTimeout := serviceConfig.ShutdownTimeout
+
 	log.Printf("Server listening at %v", ls.Addr())
 
-	if err := s.Serve(ls); err != nil {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.Serve(ls)
+	}()
+
+	select {
+	case err := <-serveErr:
 		log.Fatalf("failed to serve: %v", err)
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down, draining calls for up to %s", shutdownTimeout)
+	// tell the probes to stop routing calls here
+	healthServer.Shutdown()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		log.Printf("drain deadline reached, closing the remaining calls")
+		s.Stop()
+	}
+
+	// close the database connections once no call or loop uses them
+	background.Wait()
+	err = tenants.Close()
+	if err != nil {
+		log.Printf("failed to close the project databases: %v", err)
+	}
+	err = schemaManagementServiceDB.Db.Close()
+	if err != nil {
+		log.Printf("failed to close the database: %v", err)
 	}
+
+	log.Printf("Server stopped")
 }
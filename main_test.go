@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/isaacwassouf/schema-service/auth"
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+	"github.com/isaacwassouf/schema-service/utils"
+)
+
+// the requests below resolve from the request and metadata alone, without a
+// database
+func TestResolveResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		projectId string
+		req       any
+		want      []auth.Resource
+		wantErr   error
+	}{
+		{
+			name: "call listing the default database",
+			req:  &emptypb.Empty{},
+			want: []auth.Resource{{}},
+		},
+		{
+			name:      "table of the routed project",
+			projectId: "acme",
+			req:       &pb.DropColumnRequest{TableName: "orders", ColumnName: "total"},
+			want:      []auth.Resource{{ProjectId: "acme"}, {ProjectId: "acme", TableName: "orders"}},
+		},
+		{
+			name:      "renamed column",
+			projectId: "acme",
+			req:       &pb.RenameColumnRequest{TableName: "orders", ColumnName: "total", NewColumnName: "amount"},
+			want:      []auth.Resource{{ProjectId: "acme"}, {ProjectId: "acme", TableName: "orders"}},
+		},
+		{
+			name: "referenced tables of a new table",
+			req: &pb.CreateTableRequest{
+				TableName:   "orders",
+				ForeignKeys: []*pb.ForeignKey{{ReferenceTableName: "customers"}, {ReferenceTableName: "products"}},
+			},
+			want: []auth.Resource{{}, {TableName: "orders"}, {TableName: "customers"}, {TableName: "products"}},
+		},
+		{
+			name: "referenced table of a foreign key",
+			req:  &pb.AddForeignKeyRequest{TableName: "orders", ForeignKey: &pb.ForeignKey{ReferenceTableName: "customers"}},
+			want: []auth.Resource{{}, {TableName: "orders"}, {TableName: "customers"}},
+		},
+		{
+			name: "every side of a relationship",
+			req:  &pb.CreateRelationshipRequest{SourceTableName: "orders", TargetTableName: "products", JunctionTableName: "order_products"},
+			want: []auth.Resource{{}, {TableName: "orders"}, {TableName: "products"}, {TableName: "order_products"}},
+		},
+		{
+			name: "both tables of a clone",
+			req:  &pb.CloneTableRequest{TableName: "orders", NewTableName: "orders_copy"},
+			want: []auth.Resource{{}, {TableName: "orders"}, {TableName: "orders_copy"}},
+		},
+		{
+			name:      "provisioned project instead of the routed one",
+			projectId: "acme",
+			req:       &pb.CreateDatabaseRequest{ProjectId: "globex"},
+			want:      []auth.Resource{{ProjectId: "globex"}},
+		},
+		{
+			name:    "unknown request",
+			req:     &pb.ColumnMetadata{},
+			wantErr: auth.ErrUnknownRequest,
+		},
+	}
+
+	s := &SchemaManagementService{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx := context.Background()
+			if test.projectId != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(utils.ProjectIdMetadataKey, test.projectId))
+			}
+
+			_, got, err := s.resolveResources(ctx, "/schema_management_service.SchemaService/Test", test.req)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("resolveResources() error = %v, want %v", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resolveResources() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveResourcesInvalidProjectId(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(utils.ProjectIdMetadataKey, "../acme"))
+
+	s := &SchemaManagementService{}
+	_, _, err := s.resolveResources(ctx, "/schema_management_service.SchemaService/Test", &pb.DropTableRequest{TableName: "orders"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("resolveResources() error = %v, want code %s", err, codes.InvalidArgument)
+	}
+}
@@ -11,21 +11,23 @@ type RawColumnDetails struct {
 	MaxLength     sql.NullInt64
 	Extra         string
 	IsUnique      bool
-	IsForeign     bool
-	ForeignKey    struct {
-		ReferenceTableName  sql.NullString
-		ReferenceColumnName sql.NullString
-		OnUpdate            sql.NullString
-		OnDelete            sql.NullString
-	}
-	Precision sql.NullInt64
-	Scale     sql.NullInt64
+	Precision     sql.NullInt64
+	Scale         sql.NullInt64
+	Comment       string
+	Charset       sql.NullString
+	Collation     sql.NullString
 }
 
 type ForeignKey struct {
-	ColumnName          string
-	ReferenceTableName  string
-	ReferenceColumnName string
-	OnUpdate            string
-	OnDelete            string
+	ConstraintName       string
+	ColumnNames          []string
+	ReferenceTableName   string
+	ReferenceColumnNames []string
+	OnUpdate             string
+	OnDelete             string
+}
+
+type UniqueConstraint struct {
+	ConstraintName string
+	ColumnNames    []string
 }
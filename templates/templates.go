@@ -0,0 +1,103 @@
+// Package templates holds the DDL templates of the service. They are embedded
+// in the binary and parsed once at startup; operators can replace any of them
+// from an override directory to fit their MySQL flavor.
+package templates
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/isaacwassouf/schema-service/utils"
+)
+
+//go:embed *.tmpl
+var embedded embed.FS
+
+const templateExtension = ".tmpl"
+
+// the functions available to every template
+var funcs = template.FuncMap{
+	"Join":      strings.Join,
+	"Quote":     utils.QuoteStringLiteral,
+	"HasPrefix": strings.HasPrefix,
+}
+
+// Registry holds the parsed templates, keyed by file name without the
+// extension. It is safe for concurrent use.
+type Registry struct {
+	templates map[string]*template.Template
+}
+
+// NewRegistry parses the embedded templates, replacing those with a file of
+// the same name in the override directory, if any. Every template is parsed
+// up front so a syntax error stops the service from starting.
+func NewRegistry(overrideDir string) (*Registry, error) {
+	registry := &Registry{templates: make(map[string]*template.Template)}
+
+	entries, err := fs.ReadDir(embedded, ".")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		content, err := fs.ReadFile(embedded, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		err = registry.parse(entry.Name(), string(content))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if overrideDir == "" {
+		return registry, nil
+	}
+
+	overrides, err := filepath.Glob(filepath.Join(overrideDir, "*"+templateExtension))
+	if err != nil {
+		return nil, err
+	}
+	for _, override := range overrides {
+		// an unknown name is most likely a typo, which would silently leave
+		// the embedded template in use
+		fileName := filepath.Base(override)
+		if _, ok := registry.templates[strings.TrimSuffix(fileName, templateExtension)]; !ok {
+			return nil, fmt.Errorf("override %s does not match any template", override)
+		}
+
+		content, err := os.ReadFile(override)
+		if err != nil {
+			return nil, err
+		}
+		err = registry.parse(fileName, string(content))
+		if err != nil {
+			return nil, fmt.Errorf("invalid override %s: %w", override, err)
+		}
+	}
+
+	return registry, nil
+}
+
+func (r *Registry) parse(fileName, content string) error {
+	name := strings.TrimSuffix(fileName, templateExtension)
+	parsedTemplate, err := template.New(name).Funcs(funcs).Parse(content)
+	if err != nil {
+		return err
+	}
+	r.templates[name] = parsedTemplate
+	return nil
+}
+
+// Get returns the named template, e.g. "create_table".
+func (r *Registry) Get(name string) (*template.Template, error) {
+	parsedTemplate, ok := r.templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown template %s", name)
+	}
+	return parsedTemplate, nil
+}
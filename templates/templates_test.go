@@ -0,0 +1,106 @@
+package templates
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewRegistry(t *testing.T) {
+	renameColumnData := struct {
+		TableName     string
+		ColumnName    string
+		NewColumnName string
+	}{TableName: "orders", ColumnName: "total", NewColumnName: "amount"}
+
+	tests := []struct {
+		name string
+		// the files of the override directory, no directory when nil
+		overrides map[string]string
+		// the rendering of rename_column
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "embedded templates",
+			want: "ALTER TABLE orders\r\nRENAME COLUMN total TO amount",
+		},
+		{
+			name:      "override",
+			overrides: map[string]string{"rename_column.tmpl": "ALTER TABLE {{.TableName}} CHANGE {{.ColumnName}} {{.NewColumnName}} INT"},
+			want:      "ALTER TABLE orders CHANGE total amount INT",
+		},
+		{
+			name:      "other templates are kept",
+			overrides: map[string]string{"drop_column.tmpl": "ALTER TABLE {{.TableName}} DROP {{.ColumnName}}"},
+			want:      "ALTER TABLE orders\r\nRENAME COLUMN total TO amount",
+		},
+		{
+			name:      "files without the extension are ignored",
+			overrides: map[string]string{"rename_column.sql": "{{"},
+			want:      "ALTER TABLE orders\r\nRENAME COLUMN total TO amount",
+		},
+		{
+			name:      "override of no template",
+			overrides: map[string]string{"rename_colum.tmpl": "ALTER TABLE {{.TableName}}"},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid override",
+			overrides: map[string]string{"rename_column.tmpl": "ALTER TABLE {{.TableName"},
+			wantErr:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var overrideDir string
+			if test.overrides != nil {
+				overrideDir = t.TempDir()
+				for fileName, content := range test.overrides {
+					err := os.WriteFile(filepath.Join(overrideDir, fileName), []byte(content), 0o600)
+					if err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			registry, err := NewRegistry(overrideDir)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("NewRegistry() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewRegistry() error = %v", err)
+			}
+
+			renameColumnTemplate, err := registry.Get("rename_column")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			var renameColumnSQL bytes.Buffer
+			err = renameColumnTemplate.Execute(&renameColumnSQL, renameColumnData)
+			if err != nil {
+				t.Fatalf("Execute() error = %v", err)
+			}
+			if renameColumnSQL.String() != test.want {
+				t.Errorf("rename_column = %q, want %q", renameColumnSQL.String(), test.want)
+			}
+		})
+	}
+}
+
+func TestRegistryGetUnknown(t *testing.T) {
+	registry, err := NewRegistry("")
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+
+	_, err = registry.Get("missing")
+	if err == nil {
+		t.Errorf("Get() of an unknown template succeeded, want an error")
+	}
+}
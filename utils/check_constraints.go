@@ -0,0 +1,315 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	db "github.com/isaacwassouf/schema-service/database"
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the maximum nesting depth accepted for AND/OR check expressions
+const maxCheckExpressionDepth = 16
+
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+func IsValidIdentifier(name string) bool {
+	return identifierRegex.MatchString(name)
+}
+
+type checkExpressionBuilder struct {
+	columns []string
+	seen    map[string]bool
+}
+
+// BuildCheckExpression renders a check expression tree into SQL and returns it
+// along with the referenced column names. Only column names, comparison
+// operators and literals are accepted, so the output is safe to interpolate
+// into the check constraint templates.
+func BuildCheckExpression(expression *pb.CheckExpression) (string, []string, error) {
+	builder := &checkExpressionBuilder{seen: make(map[string]bool)}
+	checkSQL, err := builder.build(expression, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return checkSQL, builder.columns, nil
+}
+
+func (b *checkExpressionBuilder) build(expression *pb.CheckExpression, depth int) (string, error) {
+	if depth > maxCheckExpressionDepth {
+		return "", fmt.Errorf("check expression is nested too deeply")
+	}
+
+	switch expr := expression.GetExpression().(type) {
+	case *pb.CheckExpression_Comparison:
+		column, err := b.column(expr.Comparison.GetColumnName())
+		if err != nil {
+			return "", err
+		}
+
+		operator, err := getCheckComparisonOperator(expr.Comparison.GetOperator())
+		if err != nil {
+			return "", err
+		}
+
+		value, err := buildCheckLiteral(expr.Comparison.GetValue())
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s %s %s", column, operator, value), nil
+	case *pb.CheckExpression_And:
+		return b.logical(expr.And.GetExpressions(), "AND", depth)
+	case *pb.CheckExpression_Or:
+		return b.logical(expr.Or.GetExpressions(), "OR", depth)
+	case *pb.CheckExpression_In:
+		column, err := b.column(expr.In.GetColumnName())
+		if err != nil {
+			return "", err
+		}
+
+		if len(expr.In.GetValues()) == 0 {
+			return "", fmt.Errorf("IN expression requires at least one value")
+		}
+
+		values := make([]string, len(expr.In.GetValues()))
+		for i, literal := range expr.In.GetValues() {
+			values[i], err = buildCheckLiteral(literal)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		return fmt.Sprintf("%s IN (%s)", column, strings.Join(values, ", ")), nil
+	case *pb.CheckExpression_Between:
+		column, err := b.column(expr.Between.GetColumnName())
+		if err != nil {
+			return "", err
+		}
+
+		lower, err := buildCheckLiteral(expr.Between.GetLower())
+		if err != nil {
+			return "", err
+		}
+
+		upper, err := buildCheckLiteral(expr.Between.GetUpper())
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s BETWEEN %s AND %s", column, lower, upper), nil
+	case nil:
+		return "", fmt.Errorf("check expression is required")
+	default:
+		return "", fmt.Errorf("invalid check expression")
+	}
+}
+
+func (b *checkExpressionBuilder) logical(expressions []*pb.CheckExpression, operator string, depth int) (string, error) {
+	if len(expressions) < 2 {
+		return "", fmt.Errorf("%s expression requires at least two operands", operator)
+	}
+
+	operands := make([]string, len(expressions))
+	for i, expression := range expressions {
+		operand, err := b.build(expression, depth+1)
+		if err != nil {
+			return "", err
+		}
+		operands[i] = fmt.Sprintf("(%s)", operand)
+	}
+
+	return strings.Join(operands, fmt.Sprintf(" %s ", operator)), nil
+}
+
+func (b *checkExpressionBuilder) column(columnName string) (string, error) {
+	if !IsValidIdentifier(columnName) {
+		return "", fmt.Errorf("invalid column name %q", columnName)
+	}
+	if !b.seen[columnName] {
+		b.seen[columnName] = true
+		b.columns = append(b.columns, columnName)
+	}
+	return fmt.Sprintf("`%s`", columnName), nil
+}
+
+func buildCheckLiteral(literal *pb.CheckLiteral) (string, error) {
+	switch value := literal.GetValue().(type) {
+	case *pb.CheckLiteral_StringValue:
+		return QuoteStringLiteral(value.StringValue), nil
+	case *pb.CheckLiteral_IntValue:
+		return strconv.FormatInt(value.IntValue, 10), nil
+	case *pb.CheckLiteral_DoubleValue:
+		if math.IsNaN(value.DoubleValue) || math.IsInf(value.DoubleValue, 0) {
+			return "", fmt.Errorf("double literal must be a finite number")
+		}
+		return strconv.FormatFloat(value.DoubleValue, 'g', -1, 64), nil
+	case *pb.CheckLiteral_BoolValue:
+		if value.BoolValue {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case nil:
+		return "", fmt.Errorf("literal value is required")
+	default:
+		return "", fmt.Errorf("invalid literal value")
+	}
+}
+
+func getCheckComparisonOperator(operator pb.CheckComparisonOperator) (string, error) {
+	switch operator {
+	case pb.CheckComparisonOperator_EQUAL:
+		return "=", nil
+	case pb.CheckComparisonOperator_NOT_EQUAL:
+		return "<>", nil
+	case pb.CheckComparisonOperator_LESS_THAN:
+		return "<", nil
+	case pb.CheckComparisonOperator_LESS_THAN_OR_EQUAL:
+		return "<=", nil
+	case pb.CheckComparisonOperator_GREATER_THAN:
+		return ">", nil
+	case pb.CheckComparisonOperator_GREATER_THAN_OR_EQUAL:
+		return ">=", nil
+	default:
+		return "", fmt.Errorf("invalid comparison operator")
+	}
+}
+
+// QuoteStringLiteral wraps the value in single quotes, escaping backslashes
+// and quotes so it can be embedded in a DDL statement.
+func QuoteStringLiteral(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `''`)
+	return fmt.Sprintf("'%s'", value)
+}
+
+// GetCheckClauseColumns returns the column names referenced by a check clause
+// as reported by INFORMATION_SCHEMA, in order of first appearance. Backticks
+// inside string literals are not mistaken for column names.
+func GetCheckClauseColumns(checkClause string) []string {
+	var columns []string
+	seen := make(map[string]bool)
+	for i := 0; i < len(checkClause); i++ {
+		quote := checkClause[i]
+		if quote != '`' && quote != '\'' && quote != '"' {
+			continue
+		}
+
+		// find the closing quote, a doubled quote stands for itself and a
+		// backslash escapes the next character of a string literal
+		var value strings.Builder
+		for i++; i < len(checkClause); i++ {
+			if checkClause[i] == '\\' && quote != '`' && i+1 < len(checkClause) {
+				i++
+			} else if checkClause[i] == quote {
+				if i+1 < len(checkClause) && checkClause[i+1] == quote {
+					i++
+				} else {
+					break
+				}
+			}
+			value.WriteByte(checkClause[i])
+		}
+
+		column := value.String()
+		if quote != '`' || column == "" || seen[column] {
+			continue
+		}
+		seen[column] = true
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+// CheckConstraintsSupported reports whether the server enforces CHECK
+// constraints, which MySQL does starting with 8.0.16 and MariaDB starting with
+// 10.2.1. The server version is cached per connection pool.
+func CheckConstraintsSupported(tenantDB *db.SchemaManagementServiceDB) (bool, error) {
+	version, err := tenantDB.ServerVersion()
+	if err != nil {
+		return false, err
+	}
+
+	required := []int{8, 0, 16}
+	if strings.Contains(version, "MariaDB") {
+		required = []int{10, 2, 1}
+	}
+
+	// strip suffixes such as "-log", "-0ubuntu0.22.04.1" or "-MariaDB"
+	version, _, _ = strings.Cut(version, "-")
+
+	parts := strings.Split(version, ".")
+	for i, minimum := range required {
+		if i >= len(parts) {
+			return false, nil
+		}
+		part, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return false, fmt.Errorf("failed to parse server version %q", version)
+		}
+		if part != minimum {
+			return part > minimum, nil
+		}
+	}
+
+	return true, nil
+}
+
+// GetCheckConstraintTable returns the table owning the check constraint, or an
+// empty string when no such constraint exists. Check constraint names are
+// unique per schema.
+func GetCheckConstraintTable(db *sql.DB, databaseName, constraintName string) (string, error) {
+	query := "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = ? AND CONSTRAINT_NAME = ? AND CONSTRAINT_TYPE = 'CHECK'"
+	rows, err := db.Query(query, databaseName, constraintName)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var tableName string
+	for rows.Next() {
+		err = rows.Scan(&tableName)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return tableName, nil
+}
+
+func GetCheckConstraints(db *sql.DB, databaseName, tableName string) ([]*pb.CheckConstraint, error) {
+	query := `SELECT cc.CONSTRAINT_NAME, cc.CHECK_CLAUSE, tc.ENFORCED
+FROM INFORMATION_SCHEMA.CHECK_CONSTRAINTS cc
+JOIN INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+  ON cc.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+  AND cc.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+WHERE tc.CONSTRAINT_TYPE = 'CHECK' AND tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ?
+ORDER BY cc.CONSTRAINT_NAME`
+	rows, err := db.Query(query, databaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var checkConstraints []*pb.CheckConstraint
+	for rows.Next() {
+		var constraintName, checkClause, enforced string
+		err = rows.Scan(&constraintName, &checkClause, &enforced)
+		if err != nil {
+			return nil, err
+		}
+
+		checkConstraints = append(checkConstraints, &pb.CheckConstraint{
+			ConstraintName: constraintName,
+			CheckClause:    checkClause,
+			ColumnNames:    GetCheckClauseColumns(checkClause),
+			Enforced:       enforced == "YES",
+		})
+	}
+
+	return checkConstraints, rows.Err()
+}
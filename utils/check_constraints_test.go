@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+func comparison(columnName string, operator pb.CheckComparisonOperator, value *pb.CheckLiteral) *pb.CheckExpression {
+	return &pb.CheckExpression{Expression: &pb.CheckExpression_Comparison{Comparison: &pb.CheckComparison{
+		ColumnName: columnName,
+		Operator:   operator,
+		Value:      value,
+	}}}
+}
+
+func intLiteral(value int64) *pb.CheckLiteral {
+	return &pb.CheckLiteral{Value: &pb.CheckLiteral_IntValue{IntValue: value}}
+}
+
+func stringLiteral(value string) *pb.CheckLiteral {
+	return &pb.CheckLiteral{Value: &pb.CheckLiteral_StringValue{StringValue: value}}
+}
+
+func and(expressions ...*pb.CheckExpression) *pb.CheckExpression {
+	return &pb.CheckExpression{Expression: &pb.CheckExpression_And{And: &pb.CheckLogical{Expressions: expressions}}}
+}
+
+func or(expressions ...*pb.CheckExpression) *pb.CheckExpression {
+	return &pb.CheckExpression{Expression: &pb.CheckExpression_Or{Or: &pb.CheckLogical{Expressions: expressions}}}
+}
+
+func TestBuildCheckExpression(t *testing.T) {
+	deep := comparison("a", pb.CheckComparisonOperator_EQUAL, intLiteral(1))
+	for i := 0; i <= maxCheckExpressionDepth; i++ {
+		deep = and(deep, comparison("a", pb.CheckComparisonOperator_EQUAL, intLiteral(1)))
+	}
+
+	tests := []struct {
+		name        string
+		expression  *pb.CheckExpression
+		wantSQL     string
+		wantColumns []string
+		wantErr     bool
+	}{
+		{
+			name:        "comparison",
+			expression:  comparison("price", pb.CheckComparisonOperator_GREATER_THAN_OR_EQUAL, intLiteral(0)),
+			wantSQL:     "`price` >= 0",
+			wantColumns: []string{"price"},
+		},
+		{
+			name:        "string literal is escaped",
+			expression:  comparison("name", pb.CheckComparisonOperator_NOT_EQUAL, stringLiteral(`it's \ here`)),
+			wantSQL:     "`name` <> 'it''s \\\\ here'",
+			wantColumns: []string{"name"},
+		},
+		{
+			name: "nested logical operators",
+			expression: or(
+				and(
+					comparison("a", pb.CheckComparisonOperator_LESS_THAN, intLiteral(1)),
+					comparison("b", pb.CheckComparisonOperator_EQUAL, &pb.CheckLiteral{Value: &pb.CheckLiteral_BoolValue{BoolValue: true}}),
+				),
+				comparison("a", pb.CheckComparisonOperator_GREATER_THAN, &pb.CheckLiteral{Value: &pb.CheckLiteral_DoubleValue{DoubleValue: 2.5}}),
+			),
+			wantSQL:     "((`a` < 1) AND (`b` = TRUE)) OR (`a` > 2.5)",
+			wantColumns: []string{"a", "b"},
+		},
+		{
+			name: "in",
+			expression: &pb.CheckExpression{Expression: &pb.CheckExpression_In{In: &pb.CheckIn{
+				ColumnName: "status",
+				Values:     []*pb.CheckLiteral{stringLiteral("open"), stringLiteral("closed")},
+			}}},
+			wantSQL:     "`status` IN ('open', 'closed')",
+			wantColumns: []string{"status"},
+		},
+		{
+			name: "between",
+			expression: &pb.CheckExpression{Expression: &pb.CheckExpression_Between{Between: &pb.CheckBetween{
+				ColumnName: "age",
+				Lower:      intLiteral(18),
+				Upper:      intLiteral(99),
+			}}},
+			wantSQL:     "`age` BETWEEN 18 AND 99",
+			wantColumns: []string{"age"},
+		},
+		{
+			name:       "invalid column name",
+			expression: comparison("a`; DROP TABLE t; --", pb.CheckComparisonOperator_EQUAL, intLiteral(1)),
+			wantErr:    true,
+		},
+		{
+			name:       "invalid operator",
+			expression: comparison("a", pb.CheckComparisonOperator(42), intLiteral(1)),
+			wantErr:    true,
+		},
+		{
+			name:       "missing literal",
+			expression: comparison("a", pb.CheckComparisonOperator_EQUAL, &pb.CheckLiteral{}),
+			wantErr:    true,
+		},
+		{
+			name:       "infinite double",
+			expression: comparison("a", pb.CheckComparisonOperator_EQUAL, &pb.CheckLiteral{Value: &pb.CheckLiteral_DoubleValue{DoubleValue: math.Inf(1)}}),
+			wantErr:    true,
+		},
+		{
+			name:       "logical operator with one operand",
+			expression: and(comparison("a", pb.CheckComparisonOperator_EQUAL, intLiteral(1))),
+			wantErr:    true,
+		},
+		{
+			name:       "empty in",
+			expression: &pb.CheckExpression{Expression: &pb.CheckExpression_In{In: &pb.CheckIn{ColumnName: "a"}}},
+			wantErr:    true,
+		},
+		{
+			name:       "missing expression",
+			expression: &pb.CheckExpression{},
+			wantErr:    true,
+		},
+		{
+			name:       "too deep",
+			expression: deep,
+			wantErr:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			checkSQL, columns, err := BuildCheckExpression(test.expression)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("BuildCheckExpression() = %q, want an error", checkSQL)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("BuildCheckExpression() error = %v", err)
+			}
+			if checkSQL != test.wantSQL {
+				t.Errorf("BuildCheckExpression() SQL = %q, want %q", checkSQL, test.wantSQL)
+			}
+			if !reflect.DeepEqual(columns, test.wantColumns) {
+				t.Errorf("BuildCheckExpression() columns = %v, want %v", columns, test.wantColumns)
+			}
+		})
+	}
+}
+
+func TestGetCheckClauseColumns(t *testing.T) {
+	tests := []struct {
+		name        string
+		checkClause string
+		want        []string
+	}{
+		{
+			name:        "single column",
+			checkClause: "(`price` >= 0)",
+			want:        []string{"price"},
+		},
+		{
+			name:        "columns in order of first appearance",
+			checkClause: "((`b` > 0) and (`a` < `b`))",
+			want:        []string{"b", "a"},
+		},
+		{
+			name:        "backtick inside a string literal",
+			checkClause: "(`name` <> _utf8mb4'a`b')",
+			want:        []string{"name"},
+		},
+		{
+			name:        "escaped quote inside a string literal",
+			checkClause: "(`name` <> 'it\\'s `x`') and (`other` = 1)",
+			want:        []string{"name", "other"},
+		},
+		{
+			name:        "doubled backtick in a column name",
+			checkClause: "(`a``b` > 0)",
+			want:        []string{"a`b"},
+		},
+		{
+			name:        "no columns",
+			checkClause: "(1 = 1)",
+			want:        nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GetCheckClauseColumns(test.checkClause)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("GetCheckClauseColumns(%q) = %v, want %v", test.checkClause, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// the number of rows copied per statement when cloning table data
+const (
+	DefaultCloneBatchSize = 1000
+	maxCloneBatchSize     = 50000
+)
+
+// ResolveCloneBatchSize returns the requested batch size, or the default one
+// when it is zero.
+func ResolveCloneBatchSize(batchSize uint32) (int, error) {
+	if batchSize == 0 {
+		return DefaultCloneBatchSize, nil
+	}
+	if batchSize > maxCloneBatchSize {
+		return 0, fmt.Errorf("batch size must not be greater than %d", maxCloneBatchSize)
+	}
+	return int(batchSize), nil
+}
+
+// GetCloneBatchEnd returns the key of the last row of the next batch of the
+// table, the batch starting past the key after, or from the first row when it
+// is nil. It returns nil when the rows left fit in the batch.
+func GetCloneBatchEnd(db *sql.DB, databaseName, tableName string, keyColumns []string, after []any, batchSize int) ([]any, error) {
+	keyColumnList := strings.Join(keyColumns, ", ")
+	query := fmt.Sprintf("SELECT %s FROM `%s`.`%s`", keyColumnList, databaseName, tableName)
+	if after != nil {
+		query += fmt.Sprintf(" WHERE (%s) > (%s)", keyColumnList, KeyPlaceholders(len(keyColumns)))
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET %d", keyColumnList, batchSize-1)
+
+	key := make([]any, len(keyColumns))
+	keyPointers := make([]any, len(keyColumns))
+	for i := range key {
+		keyPointers[i] = &key[i]
+	}
+	err := db.QueryRow(query, after...).Scan(keyPointers...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// KeyPlaceholders returns the placeholders of a key of n columns, e.g. "?, ?".
+func KeyPlaceholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// GetTableNames returns the user tables of the database, leaving out the
+// views and the internal bookkeeping tables.
+func GetTableNames(db *sql.DB, databaseName string) ([]string, error) {
+	query := "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME"
+	rows, err := db.Query(query, databaseName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tableNames []string
+	for rows.Next() {
+		var tableName string
+		err = rows.Scan(&tableName)
+		if err != nil {
+			return nil, err
+		}
+		if IsSystemTable(tableName) {
+			continue
+		}
+		tableNames = append(tableNames, tableName)
+	}
+
+	return tableNames, rows.Err()
+}
+
+// GetInsertableColumnNames returns the columns of the table in order, leaving
+// out the generated columns which cannot be written to.
+func GetInsertableColumnNames(db *sql.DB, databaseName, tableName string) ([]string, error) {
+	query := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND GENERATION_EXPRESSION = '' ORDER BY ORDINAL_POSITION"
+	rows, err := db.Query(query, databaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columnNames []string
+	for rows.Next() {
+		var columnName string
+		err = rows.Scan(&columnName)
+		if err != nil {
+			return nil, err
+		}
+		columnNames = append(columnNames, columnName)
+	}
+
+	return columnNames, rows.Err()
+}
+
+// GetForeignKeyReferenceSchemas returns the schema of the table referenced by
+// each foreign key of the table, keyed by constraint name.
+func GetForeignKeyReferenceSchemas(db *sql.DB, databaseName, tableName string) (map[string]string, error) {
+	query := "SELECT DISTINCT CONSTRAINT_NAME, REFERENCED_TABLE_SCHEMA FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND REFERENCED_TABLE_NAME IS NOT NULL"
+	rows, err := db.Query(query, databaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referenceSchemas := make(map[string]string)
+	for rows.Next() {
+		var constraintName, referenceSchema string
+		err = rows.Scan(&constraintName, &referenceSchema)
+		if err != nil {
+			return nil, err
+		}
+		referenceSchemas[constraintName] = referenceSchema
+	}
+
+	return referenceSchemas, rows.Err()
+}
+
+// CopyColumnMetadata copies the column metadata of a table to its clone,
+// which may live in another database on the same server.
+func CopyColumnMetadata(db *sql.DB, databaseName, tableName, targetDatabaseName, targetTableName string) error {
+	query := fmt.Sprintf(
+		"INSERT INTO `%s`.%s (table_name, column_name, display_name, placeholder, pattern, min_value, max_value, hidden) SELECT ?, column_name, display_name, placeholder, pattern, min_value, max_value, hidden FROM `%s`.%s WHERE table_name = ?",
+		targetDatabaseName,
+		ColumnMetadataTableName,
+		databaseName,
+		ColumnMetadataTableName,
+	)
+	_, err := db.Exec(query, targetTableName, tableName)
+	return err
+}
+
+// CopyRelationships copies the recorded relationships of a cloned schema,
+// skipping the names already taken in the target database.
+func CopyRelationships(db *sql.DB, databaseName, targetDatabaseName string) error {
+	query := fmt.Sprintf(
+		"INSERT INTO `%[2]s`.%[3]s (name, kind, source_table_name, target_table_name, column_names, target_column_names, junction_table_name, constraint_names) SELECT name, kind, source_table_name, target_table_name, column_names, target_column_names, junction_table_name, constraint_names FROM `%[1]s`.%[3]s WHERE name NOT IN (SELECT name FROM `%[2]s`.%[3]s) ORDER BY id",
+		databaseName,
+		targetDatabaseName,
+		RelationshipsTableName,
+	)
+	_, err := db.Exec(query)
+	return err
+}
+
+// DropTables drops the tables of the database in a single statement, so the
+// foreign keys between them do not get in the way.
+func DropTables(db *sql.DB, databaseName string, tableNames []string) error {
+	qualifiedTableNames := make([]string, len(tableNames))
+	for i, tableName := range tableNames {
+		qualifiedTableNames[i] = fmt.Sprintf("`%s`.`%s`", databaseName, tableName)
+	}
+	_, err := db.Exec("DROP TABLE IF EXISTS " + strings.Join(qualifiedTableNames, ", "))
+	return err
+}
+
+// DeleteClonedColumnMetadata forgets the column metadata copied to a clone
+// that could not be completed.
+func DeleteClonedColumnMetadata(db *sql.DB, databaseName, tableName string) error {
+	query := fmt.Sprintf("DELETE FROM `%s`.%s WHERE table_name = ?", databaseName, ColumnMetadataTableName)
+	_, err := db.Exec(query, tableName)
+	return err
+}
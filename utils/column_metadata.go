@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the bookkeeping table holding the UI hints and validation rules of columns
+const ColumnMetadataTableName = "_column_metadata"
+
+// the longest values accepted for the metadata text fields
+const (
+	maxDisplayNameLength = 255
+	maxPlaceholderLength = 255
+	maxPatternLength     = 1024
+)
+
+func CreateColumnMetadataTable(db *sql.DB) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  table_name VARCHAR(64) NOT NULL,
+  column_name VARCHAR(64) NOT NULL,
+  display_name VARCHAR(255) NOT NULL DEFAULT '',
+  placeholder VARCHAR(255) NOT NULL DEFAULT '',
+  pattern TEXT,
+  min_value DOUBLE,
+  max_value DOUBLE,
+  hidden BOOLEAN NOT NULL DEFAULT FALSE,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  PRIMARY KEY (table_name, column_name)
+)`, ColumnMetadataTableName)
+	_, err := db.Exec(query)
+	return err
+}
+
+func ValidateColumnMetadata(metadata *pb.ColumnMetadata) error {
+	if metadata == nil {
+		return fmt.Errorf("metadata is required")
+	}
+	if len([]rune(metadata.DisplayName)) > maxDisplayNameLength {
+		return fmt.Errorf("display name is longer than %d characters", maxDisplayNameLength)
+	}
+	if len([]rune(metadata.Placeholder)) > maxPlaceholderLength {
+		return fmt.Errorf("placeholder is longer than %d characters", maxPlaceholderLength)
+	}
+	if len([]rune(metadata.Pattern)) > maxPatternLength {
+		return fmt.Errorf("pattern is longer than %d characters", maxPatternLength)
+	}
+	if metadata.Pattern != "" {
+		_, err := regexp.Compile(metadata.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	}
+	if metadata.Min != nil && metadata.Max != nil && *metadata.Min > *metadata.Max {
+		return fmt.Errorf("min must not be greater than max")
+	}
+	return nil
+}
+
+// SetColumnMetadata replaces the metadata of the column.
+func SetColumnMetadata(db *sql.DB, tableName, columnName string, metadata *pb.ColumnMetadata) error {
+	query := fmt.Sprintf(
+		"REPLACE INTO %s (table_name, column_name, display_name, placeholder, pattern, min_value, max_value, hidden) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		ColumnMetadataTableName,
+	)
+	_, err := db.Exec(
+		query,
+		tableName,
+		columnName,
+		metadata.DisplayName,
+		metadata.Placeholder,
+		metadata.Pattern,
+		metadata.Min,
+		metadata.Max,
+		metadata.Hidden,
+	)
+	return err
+}
+
+// GetColumnMetadata returns the metadata of the columns of the table, keyed
+// by column name. Columns without metadata are left out.
+func GetColumnMetadata(db *sql.DB, tableName string) (map[string]*pb.ColumnMetadata, error) {
+	query := fmt.Sprintf(
+		"SELECT column_name, display_name, placeholder, pattern, min_value, max_value, hidden FROM %s WHERE table_name = ?",
+		ColumnMetadataTableName,
+	)
+	rows, err := db.Query(query, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columnMetadata := make(map[string]*pb.ColumnMetadata)
+	for rows.Next() {
+		var columnName string
+		var pattern sql.NullString
+		var minValue, maxValue sql.NullFloat64
+		metadata := &pb.ColumnMetadata{}
+		err = rows.Scan(
+			&columnName,
+			&metadata.DisplayName,
+			&metadata.Placeholder,
+			&pattern,
+			&minValue,
+			&maxValue,
+			&metadata.Hidden,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		metadata.Pattern = pattern.String
+		if minValue.Valid {
+			metadata.Min = &minValue.Float64
+		}
+		if maxValue.Valid {
+			metadata.Max = &maxValue.Float64
+		}
+		columnMetadata[columnName] = metadata
+	}
+
+	return columnMetadata, rows.Err()
+}
+
+func DeleteColumnMetadata(db *sql.DB, tableName, columnName string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE table_name = ? AND column_name = ?", ColumnMetadataTableName)
+	_, err := db.Exec(query, tableName, columnName)
+	return err
+}
+
+// RenameColumnMetadata moves the metadata of a renamed column to its new name.
+func RenameColumnMetadata(db *sql.DB, tableName, columnName, newColumnName string) error {
+	query := fmt.Sprintf("UPDATE %s SET column_name = ? WHERE table_name = ? AND column_name = ?", ColumnMetadataTableName)
+	_, err := db.Exec(query, newColumnName, tableName, columnName)
+	return err
+}
+
+func DeleteTableColumnMetadata(db *sql.DB, tableName string) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE table_name = ?", ColumnMetadataTableName)
+	_, err := db.Exec(query, tableName)
+	return err
+}
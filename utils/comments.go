@@ -0,0 +1,23 @@
+package utils
+
+import "fmt"
+
+// the longest comments MySQL accepts on tables and columns
+const (
+	maxTableCommentLength  = 2048
+	maxColumnCommentLength = 1024
+)
+
+func ValidateTableComment(comment string) error {
+	if len([]rune(comment)) > maxTableCommentLength {
+		return fmt.Errorf("table comment is longer than %d characters", maxTableCommentLength)
+	}
+	return nil
+}
+
+func ValidateColumnComment(comment string) error {
+	if len([]rune(comment)) > maxColumnCommentLength {
+		return fmt.Errorf("column comment is longer than %d characters", maxColumnCommentLength)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// ConfirmationTokens hands out single use tokens that destructive operations
+// require on a second call, so they cannot be triggered by a single mistaken
+// request.
+type ConfirmationTokens struct {
+	ttl time.Duration
+
+	mu     sync.Mutex
+	tokens map[string]confirmationToken
+}
+
+type confirmationToken struct {
+	// what the token confirms, e.g. the project whose database is dropped
+	subject   string
+	expiresAt time.Time
+}
+
+func NewConfirmationTokens(ttl time.Duration) *ConfirmationTokens {
+	return &ConfirmationTokens{
+		ttl:    ttl,
+		tokens: make(map[string]confirmationToken),
+	}
+}
+
+// Issue returns a new token confirming the subject.
+func (c *ConfirmationTokens) Issue(subject string) (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// forget the tokens that were never used
+	now := time.Now()
+	for t, confirmation := range c.tokens {
+		if now.After(confirmation.expiresAt) {
+			delete(c.tokens, t)
+		}
+	}
+
+	c.tokens[token] = confirmationToken{subject: subject, expiresAt: now.Add(c.ttl)}
+	return token, nil
+}
+
+// Consume reports whether the token was issued for the subject and has not
+// expired. A token can only be consumed once.
+func (c *ConfirmationTokens) Consume(subject, token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	confirmation, ok := c.tokens[token]
+	if !ok || confirmation.subject != subject {
+		return false
+	}
+	delete(c.tokens, token)
+
+	return time.Now().Before(confirmation.expiresAt)
+}
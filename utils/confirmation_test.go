@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConfirmationTokens(t *testing.T) {
+	type consume struct {
+		subject string
+		// the issued token is used when empty
+		token string
+		want  bool
+	}
+
+	tests := []struct {
+		name     string
+		ttl      time.Duration
+		consumes []consume
+	}{
+		{
+			name:     "issued token",
+			ttl:      time.Minute,
+			consumes: []consume{{subject: "project", want: true}},
+		},
+		{
+			name: "single use",
+			ttl:  time.Minute,
+			consumes: []consume{
+				{subject: "project", want: true},
+				{subject: "project", want: false},
+			},
+		},
+		{
+			name: "other subject",
+			ttl:  time.Minute,
+			consumes: []consume{
+				{subject: "other", want: false},
+				{subject: "project", want: true},
+			},
+		},
+		{
+			name:     "unknown token",
+			ttl:      time.Minute,
+			consumes: []consume{{subject: "project", token: "unknown", want: false}},
+		},
+		{
+			name: "expired token",
+			ttl:  -time.Second,
+			consumes: []consume{
+				{subject: "project", want: false},
+				{subject: "project", want: false},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tokens := NewConfirmationTokens(test.ttl)
+			issued, err := tokens.Issue("project")
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+
+			for i, c := range test.consumes {
+				token := c.token
+				if token == "" {
+					token = issued
+				}
+				if got := tokens.Consume(c.subject, token); got != c.want {
+					t.Errorf("Consume() #%d = %v, want %v", i, got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmationTokensUnique(t *testing.T) {
+	tokens := NewConfirmationTokens(time.Minute)
+	first, err := tokens.Issue("project")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	second, err := tokens.Issue("project")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if first == second {
+		t.Fatalf("Issue() returned the same token twice")
+	}
+
+	// both stay valid until used
+	if !tokens.Consume("project", first) || !tokens.Consume("project", second) {
+		t.Errorf("Consume() rejected a token issued for the subject")
+	}
+}
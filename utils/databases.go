@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the longest user name MySQL accepts
+const maxUsernameLength = 32
+
+// the prefix of the database names derived from project ids
+const projectDatabasePrefix = "project_"
+
+// the server schemas project databases must never be mistaken for
+var reservedDatabaseNames = []string{"mysql", "sys", "information_schema", "performance_schema"}
+
+// GetProjectDatabaseName returns the requested database name, or one derived
+// from the project id when it is empty.
+func GetProjectDatabaseName(projectId, databaseName string) (string, error) {
+	if databaseName == "" {
+		databaseName = projectDatabasePrefix + strings.ReplaceAll(projectId, "-", "_")
+	}
+	if !IsValidIdentifier(databaseName) {
+		return "", fmt.Errorf("invalid database name %q", databaseName)
+	}
+	for _, reservedDatabaseName := range reservedDatabaseNames {
+		if strings.EqualFold(databaseName, reservedDatabaseName) {
+			return "", fmt.Errorf("database name %q is reserved", databaseName)
+		}
+	}
+	return databaseName, nil
+}
+
+// GetProjectUsername returns the name of the MySQL user scoped to the project
+// database.
+func GetProjectUsername(databaseName string) string {
+	return truncateName(databaseName, maxUsernameLength)
+}
+
+// GeneratePassword returns a random password made of URL safe characters, so
+// it can be quoted into the user DDL as is.
+func GeneratePassword() (string, error) {
+	buf := make([]byte, 24)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func CheckDatabaseExists(db *sql.DB, databaseName string) (bool, error) {
+	rows, err := db.Query("SELECT SCHEMA_NAME FROM INFORMATION_SCHEMA.SCHEMATA WHERE SCHEMA_NAME = ?", databaseName)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+// CreateProjectDatabase creates the database with the resolved charset and
+// collation, or the server defaults when they are empty.
+func CreateProjectDatabase(db *sql.DB, databaseName, charset, collation string) error {
+	query := fmt.Sprintf("CREATE DATABASE `%s`", databaseName)
+	if charset != "" {
+		query += " CHARACTER SET " + charset
+	}
+	if collation != "" {
+		query += " COLLATE " + collation
+	}
+	_, err := db.Exec(query)
+	return err
+}
+
+func DropProjectDatabase(db *sql.DB, databaseName string) error {
+	_, err := db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", databaseName))
+	return err
+}
+
+func CheckProjectUserExists(db *sql.DB, username, host string) (bool, error) {
+	rows, err := db.Query("SELECT User FROM mysql.user WHERE User = ? AND Host = ?", username, host)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+// CreateProjectUser creates the user and grants it every privilege on the
+// project database, and nothing outside of it. It fails when the user exists
+// already, and drops the user it created when the grant fails, so on error no
+// user is left behind by the call.
+func CreateProjectUser(db *sql.DB, username, host, password, databaseName string) error {
+	account := fmt.Sprintf("%s@%s", QuoteStringLiteral(username), QuoteStringLiteral(host))
+
+	_, err := db.Exec(fmt.Sprintf("CREATE USER %s IDENTIFIED BY %s", account, QuoteStringLiteral(password)))
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Exec(fmt.Sprintf("GRANT ALL PRIVILEGES ON `%s`.* TO %s", databaseName, account))
+	if err != nil {
+		dropErr := DropProjectUser(db, username, host)
+		if dropErr != nil {
+			return fmt.Errorf("%w, and failed to drop the user: %v", err, dropErr)
+		}
+		return err
+	}
+	return nil
+}
+
+func DropProjectUser(db *sql.DB, username, host string) error {
+	_, err := db.Exec(fmt.Sprintf("DROP USER IF EXISTS %s@%s", QuoteStringLiteral(username), QuoteStringLiteral(host)))
+	return err
+}
+
+// AddTenant registers the project database for routing.
+func AddTenant(controlDB *sql.DB, projectId, databaseName, username string) error {
+	query := fmt.Sprintf("INSERT INTO %s (project_id, database_name, username) VALUES (?, ?, ?)", TenantsTableName)
+	_, err := controlDB.Exec(query, projectId, databaseName, username)
+	return err
+}
+
+// StartTenantDrop unregisters the project database, so no request is routed
+// to it anymore, and records the drop until FinishTenantDrop is called.
+func StartTenantDrop(controlDB *sql.DB, projectId string) error {
+	tx, err := controlDB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (project_id, database_name, username) SELECT project_id, database_name, username FROM %s WHERE project_id = ?",
+		TenantDropsTableName,
+		TenantsTableName,
+	)
+	_, err = tx.Exec(query, projectId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE project_id = ?", TenantsTableName), projectId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetTenantDrop returns the project database whose drop has not finished, or
+// nil when there is none.
+func GetTenantDrop(controlDB *sql.DB, projectId string) (*pb.Database, error) {
+	query := fmt.Sprintf("SELECT project_id, database_name, username FROM %s WHERE project_id = ?", TenantDropsTableName)
+	database := &pb.Database{}
+	err := controlDB.QueryRow(query, projectId).Scan(&database.ProjectId, &database.Name, &database.Username)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+// CheckTenantDropPending reports whether the drop of the project database, or
+// of a database with the name, has not finished.
+func CheckTenantDropPending(controlDB *sql.DB, projectId, databaseName string) (bool, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE project_id = ? OR database_name = ?", TenantDropsTableName)
+	var count int
+	err := controlDB.QueryRow(query, projectId, databaseName).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func FinishTenantDrop(controlDB *sql.DB, projectId string) error {
+	_, err := controlDB.Exec(fmt.Sprintf("DELETE FROM %s WHERE project_id = ?", TenantDropsTableName), projectId)
+	return err
+}
+
+// GetTenantDatabases returns the registered project databases with their
+// charset, collation and size, or only the one of the project when the
+// project id is not empty.
+func GetTenantDatabases(controlDB *sql.DB, projectId string) ([]*pb.Database, error) {
+	query := fmt.Sprintf(`SELECT
+  t.project_id,
+  t.database_name,
+  t.username,
+  s.DEFAULT_CHARACTER_SET_NAME,
+  s.DEFAULT_COLLATION_NAME,
+  COUNT(it.TABLE_NAME),
+  COALESCE(SUM(it.DATA_LENGTH + it.INDEX_LENGTH), 0),
+  t.created_at
+FROM %s t
+LEFT JOIN INFORMATION_SCHEMA.SCHEMATA s ON s.SCHEMA_NAME = t.database_name
+LEFT JOIN INFORMATION_SCHEMA.TABLES it ON it.TABLE_SCHEMA = t.database_name`, TenantsTableName)
+	var args []any
+	if projectId != "" {
+		query += "\nWHERE t.project_id = ?"
+		args = append(args, projectId)
+	}
+	query += `
+GROUP BY t.project_id, t.database_name, t.username, s.DEFAULT_CHARACTER_SET_NAME, s.DEFAULT_COLLATION_NAME, t.created_at
+ORDER BY t.project_id`
+
+	rows, err := controlDB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var databases []*pb.Database
+	for rows.Next() {
+		var charset, collation sql.NullString
+		database := &pb.Database{}
+		err = rows.Scan(
+			&database.ProjectId,
+			&database.Name,
+			&database.Username,
+			&charset,
+			&collation,
+			&database.TableCount,
+			&database.SizeBytes,
+			&database.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		database.Charset = charset.String
+		database.Collation = collation.String
+		databases = append(databases, database)
+	}
+
+	return databases, rows.Err()
+}
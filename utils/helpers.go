@@ -1,48 +1,30 @@
 package utils
 
 import (
+	"crypto/sha1"
 	"database/sql"
+	"encoding/hex"
 	"fmt"
-	"os"
 	"strings"
 
-	"github.com/joho/godotenv"
-
 	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
 	"github.com/isaacwassouf/schema-service/shared"
 )
 
-func LoadEnvVarsFromFile() error {
-	environment := GetEnvVar("GO_ENV", "development")
-	if environment == "development" {
-		err := godotenv.Load()
-		if err != nil {
-			return err
-		}
-		return nil
-	}
-	return nil
-}
+// the maximum length of a MySQL identifier
+const maxIdentifierLength = 64
 
-func GetEnvVar(key string, defaultValue string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists {
-		return defaultValue
-	}
-	return value
-}
-
-func ReadTemplateFile(filepath string) (string, error) {
-	templateFileBytes, err := os.ReadFile(filepath)
-	if err != nil {
-		return "", err
-	}
-	return string(templateFileBytes), nil
-}
+// the prefixes of the generated constraint and index names
+const (
+	ForeignKeyPrefix       = "fk"
+	UniqueConstraintPrefix = "uq"
+	IndexPrefix            = "idx"
+	CheckConstraintPrefix  = "ck"
+)
 
-func CheckTableExists(db *sql.DB, tableName string) (bool, error) {
-	query := fmt.Sprintf("SHOW TABLES LIKE '%s'", tableName)
-	rows, err := db.Query(query)
+func CheckTableExists(db *sql.DB, databaseName, tableName string) (bool, error) {
+	query := "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?"
+	rows, err := db.Query(query, databaseName, tableName)
 	if err != nil {
 		return false, err
 	}
@@ -51,9 +33,9 @@ func CheckTableExists(db *sql.DB, tableName string) (bool, error) {
 	return rows.Next(), nil
 }
 
-func CheckColumnExists(db *sql.DB, tableName string, columnName string) (bool, error) {
-	query := fmt.Sprintf("SHOW COLUMNS FROM %s LIKE '%s'", tableName, columnName)
-	rows, err := db.Query(query)
+func CheckColumnExists(db *sql.DB, databaseName, tableName string, columnName string) (bool, error) {
+	query := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	rows, err := db.Query(query, databaseName, tableName, columnName)
 	if err != nil {
 		return false, err
 	}
@@ -334,11 +316,8 @@ func MapReferentialActionsStringToEnum(rawKey *shared.ForeignKey, foreignKey *pb
 	}
 }
 
-func GetColumnTypeFromName(db *sql.DB, tableName, columnName string) (string, error) {
+func GetColumnTypeFromName(db *sql.DB, databaseName, tableName, columnName string) (string, error) {
 	query := "SELECT COLUMN_TYPE FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
-	// get the database name from the environment variables
-	databaseName := GetEnvVar("MYSQL_DATABASE", "database")
-
 	rows, err := db.Query(query, databaseName, tableName, columnName)
 	if err != nil {
 		return "", err
@@ -356,24 +335,555 @@ func GetColumnTypeFromName(db *sql.DB, tableName, columnName string) (string, er
 	return columnType, nil
 }
 
-func GetForeignKeyConstraint(db *sql.DB, tableName, columnName string) (string, error) {
-	// get the database name from the environment variables
-	databaseName := GetEnvVar("MYSQL_DATABASE", "database")
+// GetConstraintName builds the deterministic name of a constraint or index,
+// e.g. fk_orders_customer_id.
+func GetConstraintName(prefix, tableName string, columnNames []string) string {
+	name := fmt.Sprintf("%s_%s_%s", prefix, tableName, strings.Join(columnNames, "_"))
+	return truncateIdentifier(name)
+}
 
-	query := "SELECT CONSTRAINT_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE WHERE TABLE_NAME = ? AND COLUMN_NAME = ? AND TABLE_SCHEMA = ?"
-	rows, err := db.Query(query, tableName, columnName, databaseName)
+// truncateIdentifier shortens names longer than MySQL allows, replacing the
+// tail with a hash of the full name so the result stays unique and stable.
+func truncateIdentifier(name string) string {
+	return truncateName(name, maxIdentifierLength)
+}
+
+func truncateName(name string, maxLength int) string {
+	if len(name) <= maxLength {
+		return name
+	}
+
+	sum := sha1.Sum([]byte(name))
+	suffix := hex.EncodeToString(sum[:4])
+	return fmt.Sprintf("%s_%s", name[:maxLength-len(suffix)-1], suffix)
+}
+
+// ResolveConstraintName returns the caller provided name when set, or the
+// deterministic name otherwise.
+func ResolveConstraintName(name, prefix, tableName string, columnNames []string) (string, error) {
+	if name == "" {
+		return GetConstraintName(prefix, tableName, columnNames), nil
+	}
+	if !IsValidIdentifier(name) {
+		return "", fmt.Errorf("invalid constraint name %q", name)
+	}
+	return name, nil
+}
+
+// GetForeignKeyColumnNames returns the ordered local and referenced columns of
+// the foreign key, falling back to the single column fields when the lists are
+// empty and then to the primary key of the referenced table.
+func GetForeignKeyColumnNames(db *sql.DB, databaseName string, foreignKey *pb.ForeignKey, config *SystemConfig) ([]string, []string, error) {
+	columnNames := foreignKey.ColumnNames
+	if len(columnNames) == 0 && foreignKey.ColumnName != "" {
+		columnNames = []string{foreignKey.ColumnName}
+	}
+	if len(columnNames) == 0 {
+		return nil, nil, fmt.Errorf("foreign key column is required")
+	}
+
+	// the users table is always referenced through its id
+	if config.IsUsersTable(foreignKey.ReferenceTableName) {
+		if len(columnNames) != 1 {
+			return nil, nil, fmt.Errorf("foreign keys to %s must have exactly one column", config.UsersTable)
+		}
+		return columnNames, []string{UsersIdColumnName}, nil
+	}
+
+	referenceColumnNames := foreignKey.ReferenceColumnNames
+	if len(referenceColumnNames) == 0 && foreignKey.ReferenceColumnName != "" {
+		referenceColumnNames = []string{foreignKey.ReferenceColumnName}
+	}
+	if len(referenceColumnNames) == 0 {
+		// whatever the primary key strategy of the referenced table, its key
+		// columns are what a foreign key points at by default
+		primaryKeyColumns, err := GetPrimaryKeyColumns(db, databaseName, foreignKey.ReferenceTableName)
+		if err != nil {
+			return nil, nil, err
+		}
+		referenceColumnNames = primaryKeyColumns
+	}
+	if len(referenceColumnNames) != len(columnNames) {
+		return nil, nil, fmt.Errorf("foreign key must reference as many columns as it has")
+	}
+
+	return columnNames, referenceColumnNames, nil
+}
+
+// CheckColumnsIndexed reports whether the columns, in order, form the leftmost
+// prefix of an index on the table, as required to back a foreign key.
+func CheckColumnsIndexed(db *sql.DB, databaseName, tableName string, columnNames []string) (bool, error) {
+	query := "SELECT INDEX_NAME, COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY INDEX_NAME, SEQ_IN_INDEX"
+	rows, err := db.Query(query, databaseName, tableName)
 	if err != nil {
-		return "", err
+		return false, err
+	}
+	defer rows.Close()
+
+	indexes := make(map[string][]string)
+	for rows.Next() {
+		var indexName, columnName string
+		err = rows.Scan(&indexName, &columnName)
+		if err != nil {
+			return false, err
+		}
+		indexes[indexName] = append(indexes[indexName], columnName)
+	}
+	if err = rows.Err(); err != nil {
+		return false, err
+	}
+
+	for _, indexColumns := range indexes {
+		if isColumnPrefix(columnNames, indexColumns) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetOrphanValues returns up to limit distinct tuples of the columns that have
+// no matching row in the referenced table. Rows with a null in any of the
+// columns are not checked by the foreign key and are skipped.
+func GetOrphanValues(db *sql.DB, tableName string, columnNames []string, referenceTableName string, referenceColumnNames []string, limit int) ([]string, error) {
+	selectColumns := make([]string, len(columnNames))
+	joinConditions := make([]string, len(columnNames))
+	notNullConditions := make([]string, len(columnNames))
+	for i, columnName := range columnNames {
+		selectColumns[i] = fmt.Sprintf("c.%s", columnName)
+		joinConditions[i] = fmt.Sprintf("c.%s = r.%s", columnName, referenceColumnNames[i])
+		notNullConditions[i] = fmt.Sprintf("c.%s IS NOT NULL", columnName)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT DISTINCT %s FROM %s c LEFT JOIN %s r ON %s WHERE %s AND r.%s IS NULL LIMIT %d",
+		strings.Join(selectColumns, ", "),
+		tableName,
+		referenceTableName,
+		strings.Join(joinConditions, " AND "),
+		strings.Join(notNullConditions, " AND "),
+		referenceColumnNames[0],
+		limit,
+	)
+	rows, err := db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		tuple := make([]sql.NullString, len(columnNames))
+		scanArgs := make([]any, len(columnNames))
+		for i := range tuple {
+			scanArgs[i] = &tuple[i]
+		}
+		err = rows.Scan(scanArgs...)
+		if err != nil {
+			return nil, err
+		}
+
+		tupleValues := make([]string, len(tuple))
+		for i, value := range tuple {
+			tupleValues[i] = value.String
+		}
+		if len(tupleValues) == 1 {
+			values = append(values, tupleValues[0])
+		} else {
+			values = append(values, fmt.Sprintf("(%s)", strings.Join(tupleValues, ", ")))
+		}
+	}
+
+	return values, rows.Err()
+}
+
+// the columns selected by the foreign key listing queries
+const foreignKeyColumnsQuery = `SELECT kcu.TABLE_NAME, kcu.CONSTRAINT_NAME, kcu.COLUMN_NAME, kcu.REFERENCED_TABLE_NAME, kcu.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE
+FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r
+  ON kcu.CONSTRAINT_SCHEMA = r.CONSTRAINT_SCHEMA
+  AND kcu.CONSTRAINT_NAME = r.CONSTRAINT_NAME
+`
+
+// GetForeignKeys returns the foreign keys declared on the table, with their
+// columns grouped by constraint in key order.
+func GetForeignKeys(db *sql.DB, databaseName, tableName string) ([]*pb.ForeignKey, error) {
+	query := foreignKeyColumnsQuery + `WHERE kcu.TABLE_SCHEMA = ? AND kcu.TABLE_NAME = ? AND kcu.REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION`
+	dependencies, err := queryForeignKeys(db, query, databaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	foreignKeys := make([]*pb.ForeignKey, len(dependencies))
+	for i, dependency := range dependencies {
+		foreignKeys[i] = dependency.ForeignKey
+	}
+
+	return foreignKeys, nil
+}
+
+// GetInboundForeignKeys returns the foreign keys of any table in the schema,
+// including the table itself, that reference the table.
+func GetInboundForeignKeys(db *sql.DB, databaseName, tableName string) ([]*pb.TableDependency, error) {
+	query := foreignKeyColumnsQuery + `WHERE r.CONSTRAINT_SCHEMA = ? AND r.REFERENCED_TABLE_NAME = ?
+ORDER BY kcu.TABLE_NAME, kcu.CONSTRAINT_NAME, kcu.ORDINAL_POSITION`
+
+	return queryForeignKeys(db, query, databaseName, tableName)
+}
+
+// queryForeignKeys runs a foreign key listing query and groups its rows by
+// table and constraint. The rows must be ordered by constraint and position.
+func queryForeignKeys(db *sql.DB, query string, args ...any) ([]*pb.TableDependency, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dependencies []*pb.TableDependency
+	for rows.Next() {
+		var tableName, constraintName, columnName, referenceTableName, referenceColumnName, onUpdate, onDelete string
+		err = rows.Scan(&tableName, &constraintName, &columnName, &referenceTableName, &referenceColumnName, &onUpdate, &onDelete)
+		if err != nil {
+			return nil, err
+		}
+
+		// start a new foreign key when the constraint changes
+		if len(dependencies) == 0 ||
+			dependencies[len(dependencies)-1].TableName != tableName ||
+			dependencies[len(dependencies)-1].ForeignKey.ConstraintName != constraintName {
+			foreignKey := &pb.ForeignKey{
+				ConstraintName:     constraintName,
+				ReferenceTableName: referenceTableName,
+			}
+			// map the referential actions string to the enum
+			MapReferentialActionsStringToEnum(&shared.ForeignKey{
+				OnUpdate: onUpdate,
+				OnDelete: onDelete,
+			}, foreignKey)
+			dependencies = append(dependencies, &pb.TableDependency{
+				TableName:  tableName,
+				ForeignKey: foreignKey,
+			})
+		}
+
+		foreignKey := dependencies[len(dependencies)-1].ForeignKey
+		foreignKey.ColumnNames = append(foreignKey.ColumnNames, columnName)
+		foreignKey.ReferenceColumnNames = append(foreignKey.ReferenceColumnNames, referenceColumnName)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// keep the single column fields filled for single column keys
+	for _, dependency := range dependencies {
+		if len(dependency.ForeignKey.ColumnNames) == 1 {
+			dependency.ForeignKey.ColumnName = dependency.ForeignKey.ColumnNames[0]
+			dependency.ForeignKey.ReferenceColumnName = dependency.ForeignKey.ReferenceColumnNames[0]
+		}
+	}
+
+	return dependencies, nil
+}
+
+// GetUniqueConstraints returns the unique constraints declared on the table,
+// with their columns in index order.
+func GetUniqueConstraints(db *sql.DB, databaseName, tableName string) ([]*pb.UniqueConstraint, error) {
+	query := `SELECT tc.CONSTRAINT_NAME, kcu.COLUMN_NAME
+FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+  ON tc.CONSTRAINT_SCHEMA = kcu.CONSTRAINT_SCHEMA
+  AND tc.TABLE_NAME = kcu.TABLE_NAME
+  AND tc.CONSTRAINT_NAME = kcu.CONSTRAINT_NAME
+WHERE tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ? AND tc.CONSTRAINT_TYPE = 'UNIQUE'
+ORDER BY tc.CONSTRAINT_NAME, kcu.ORDINAL_POSITION`
+	rows, err := db.Query(query, databaseName, tableName)
+	if err != nil {
+		return nil, err
 	}
 	defer rows.Close()
 
-	var constraintName string
+	var uniqueConstraints []*pb.UniqueConstraint
 	for rows.Next() {
-		err = rows.Scan(&constraintName)
+		var constraintName, columnName string
+		err = rows.Scan(&constraintName, &columnName)
+		if err != nil {
+			return nil, err
+		}
+
+		// start a new unique constraint when the constraint changes
+		if len(uniqueConstraints) == 0 || uniqueConstraints[len(uniqueConstraints)-1].ConstraintName != constraintName {
+			uniqueConstraints = append(uniqueConstraints, &pb.UniqueConstraint{ConstraintName: constraintName})
+		}
+
+		uniqueConstraint := uniqueConstraints[len(uniqueConstraints)-1]
+		uniqueConstraint.ColumnNames = append(uniqueConstraint.ColumnNames, columnName)
+	}
+
+	return uniqueConstraints, rows.Err()
+}
+
+// GetUnneededForeignKeyIndex returns the index that was created to back the
+// foreign key, either implicitly by MySQL under the constraint name or by
+// AddForeignKey under the generated index name, provided no other foreign key
+// on the table still relies on it. It returns an empty string otherwise.
+func GetUnneededForeignKeyIndex(db *sql.DB, databaseName, tableName, constraintName string) (string, error) {
+	foreignKeys, err := GetForeignKeys(db, databaseName, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []string
+	var otherForeignKeys []*pb.ForeignKey
+	for _, foreignKey := range foreignKeys {
+		if foreignKey.ConstraintName == constraintName {
+			candidates = []string{constraintName, GetConstraintName(IndexPrefix, tableName, foreignKey.ColumnNames)}
+			continue
+		}
+		otherForeignKeys = append(otherForeignKeys, foreignKey)
+	}
+
+	query := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = ? AND NON_UNIQUE = 1 ORDER BY SEQ_IN_INDEX"
+	for _, indexName := range candidates {
+		rows, err := db.Query(query, databaseName, tableName, indexName)
 		if err != nil {
 			return "", err
 		}
+
+		var indexColumns []string
+		for rows.Next() {
+			var columnName string
+			err = rows.Scan(&columnName)
+			if err != nil {
+				rows.Close()
+				return "", err
+			}
+			indexColumns = append(indexColumns, columnName)
+		}
+		rows.Close()
+
+		if len(indexColumns) == 0 {
+			continue
+		}
+
+		// keep the index when another foreign key is backed by it
+		needed := false
+		for _, foreignKey := range otherForeignKeys {
+			if isColumnPrefix(foreignKey.ColumnNames, indexColumns) {
+				needed = true
+				break
+			}
+		}
+		if !needed {
+			return indexName, nil
+		}
+	}
+
+	return "", nil
+}
+
+// isColumnPrefix reports whether the columns are the leftmost columns of the
+// index columns, in order.
+func isColumnPrefix(columnNames, indexColumns []string) bool {
+	if len(columnNames) > len(indexColumns) {
+		return false
+	}
+	for i, columnName := range columnNames {
+		if !strings.EqualFold(indexColumns[i], columnName) {
+			return false
+		}
+	}
+	return true
+}
+
+type dropTablePlanner struct {
+	// returns the foreign keys referencing a table, GetInboundForeignKeys
+	inboundForeignKeys func(tableName string) ([]*pb.TableDependency, error)
+	tableName          string
+	keepColumns        bool
+	visited            map[string]bool
+	plan               []*pb.TableDependency
+}
+
+// GetDropTablePlan returns the foreign keys that must be dropped before the
+// table can be, in the order they have to be dropped. Unless the referencing
+// columns are kept, foreign keys pointing at those columns are dropped first.
+func GetDropTablePlan(db *sql.DB, databaseName, tableName string, keepColumns bool) ([]*pb.TableDependency, error) {
+	inboundForeignKeys := func(tableName string) ([]*pb.TableDependency, error) {
+		return GetInboundForeignKeys(db, databaseName, tableName)
+	}
+	return planDropTable(inboundForeignKeys, tableName, keepColumns)
+}
+
+func planDropTable(inboundForeignKeys func(tableName string) ([]*pb.TableDependency, error), tableName string, keepColumns bool) ([]*pb.TableDependency, error) {
+	planner := &dropTablePlanner{
+		inboundForeignKeys: inboundForeignKeys,
+		tableName:          tableName,
+		keepColumns:        keepColumns,
+		visited:            make(map[string]bool),
+	}
+
+	err := planner.visit(tableName, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return planner.plan, nil
+}
+
+// visit plans the foreign keys referencing the table, restricted to the given
+// referenced columns when columnNames is not nil.
+func (p *dropTablePlanner) visit(tableName string, columnNames []string) error {
+	dependencies, err := p.inboundForeignKeys(tableName)
+	if err != nil {
+		return err
+	}
+
+	for _, dependency := range dependencies {
+		// foreign keys of the dropped table go away with it, and the trash
+		// is never altered to make room
+		if dependency.TableName == p.tableName || IsSystemTable(dependency.TableName) {
+			continue
+		}
+		if columnNames != nil && !hasCommonColumn(dependency.ForeignKey.ReferenceColumnNames, columnNames) {
+			continue
+		}
+
+		key := fmt.Sprintf("%s.%s", dependency.TableName, dependency.ForeignKey.ConstraintName)
+		if p.visited[key] {
+			continue
+		}
+		p.visited[key] = true
+
+		// the referencing columns are dropped as well, so anything that
+		// references them has to go first
+		if !p.keepColumns {
+			err = p.visit(dependency.TableName, dependency.ForeignKey.ColumnNames)
+			if err != nil {
+				return err
+			}
+		}
+
+		p.plan = append(p.plan, dependency)
+	}
+
+	return nil
+}
+
+func hasCommonColumn(columnNames, otherColumnNames []string) bool {
+	for _, columnName := range columnNames {
+		for _, otherColumnName := range otherColumnNames {
+			if strings.EqualFold(columnName, otherColumnName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GetPrimaryKeyColumns returns the primary key columns of the table in key
+// order.
+func GetPrimaryKeyColumns(db *sql.DB, databaseName, tableName string) ([]string, error) {
+	query := "SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND INDEX_NAME = 'PRIMARY' ORDER BY SEQ_IN_INDEX"
+	rows, err := db.Query(query, databaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columnNames []string
+	for rows.Next() {
+		var columnName string
+		err = rows.Scan(&columnName)
+		if err != nil {
+			return nil, err
+		}
+		columnNames = append(columnNames, columnName)
+	}
+
+	return columnNames, rows.Err()
+}
+
+// ColumnDefinition is the definition of an existing column as accepted by ADD
+// COLUMN and MODIFY COLUMN.
+type ColumnDefinition struct {
+	// the type, generation, charset, nullability and default of the column
+	Definition string
+	Comment    string
+	// generated columns hold no data of their own, nothing can be written to
+	// them
+	Generated bool
+}
+
+// String returns the definition followed by the comment, if any.
+func (d *ColumnDefinition) String() string {
+	if d.Comment == "" {
+		return d.Definition
+	}
+	return fmt.Sprintf("%s COMMENT %s", d.Definition, QuoteStringLiteral(d.Comment))
+}
+
+// GetColumnDefinition rebuilds the definition of the column from
+// INFORMATION_SCHEMA, generation expression and comment included.
+func GetColumnDefinition(db *sql.DB, databaseName, tableName, columnName string) (*ColumnDefinition, error) {
+	query := "SELECT COLUMN_TYPE, CHARACTER_SET_NAME, COLLATION_NAME, IS_NULLABLE, COLUMN_DEFAULT, EXTRA, GENERATION_EXPRESSION, COLUMN_COMMENT FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+
+	var columnType, isNullable, extra, comment string
+	var charset, collation, columnDefault, generationExpression sql.NullString
+	err := db.QueryRow(query, databaseName, tableName, columnName).Scan(&columnType, &charset, &collation, &isNullable, &columnDefault, &extra, &generationExpression, &comment)
+	if err != nil {
+		return nil, err
+	}
+	extra = strings.ToLower(extra)
+
+	definition := columnType
+	if charset.Valid {
+		definition += fmt.Sprintf(" CHARACTER SET %s", charset.String)
+	}
+	if collation.Valid {
+		definition += fmt.Sprintf(" COLLATE %s", collation.String)
+	}
+
+	// generated columns take an expression instead of a default, MySQL
+	// reports the quotes of its string literals escaped with backslashes
+	generated := generationExpression.String != ""
+	if generated {
+		expression := strings.ReplaceAll(generationExpression.String, `\'`, `'`)
+		kind := "VIRTUAL"
+		if strings.Contains(extra, "stored generated") {
+			kind = "STORED"
+		}
+		definition += fmt.Sprintf(" GENERATED ALWAYS AS (%s) %s", expression, kind)
+	}
+
+	if isNullable == "NO" {
+		definition += " NOT NULL"
+	}
+
+	if columnDefault.Valid && !generated {
+		// generated defaults are expressions, not literals, and only
+		// CURRENT_TIMESTAMP may be written without parentheses
+		if strings.Contains(extra, "default_generated") {
+			if strings.HasPrefix(strings.ToUpper(columnDefault.String), "CURRENT_TIMESTAMP") {
+				definition += fmt.Sprintf(" DEFAULT %s", columnDefault.String)
+			} else {
+				definition += fmt.Sprintf(" DEFAULT (%s)", columnDefault.String)
+			}
+		} else {
+			definition += fmt.Sprintf(" DEFAULT %s", QuoteStringLiteral(columnDefault.String))
+		}
+	}
+
+	if strings.Contains(extra, "auto_increment") {
+		definition += " AUTO_INCREMENT"
+	}
+	if strings.Contains(extra, "on update current_timestamp") {
+		definition += " ON UPDATE CURRENT_TIMESTAMP"
+	}
+	if strings.Contains(extra, "invisible") {
+		definition += " INVISIBLE"
 	}
 
-	return constraintName, nil
+	return &ColumnDefinition{Definition: definition, Comment: comment, Generated: generated}, nil
 }
@@ -0,0 +1,186 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+func TestTruncateName(t *testing.T) {
+	long := "fk_" + strings.Repeat("a", 70)
+	truncated := truncateName(long, maxIdentifierLength)
+
+	tests := []struct {
+		name      string
+		input     string
+		maxLength int
+		want      string
+	}{
+		{name: "short name is kept", input: "fk_orders_user_id", maxLength: 64, want: "fk_orders_user_id"},
+		{name: "name at the limit is kept", input: strings.Repeat("a", 64), maxLength: 64, want: strings.Repeat("a", 64)},
+		{name: "long name is hashed", input: long, maxLength: maxIdentifierLength, want: truncated},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := truncateName(test.input, test.maxLength)
+			if got != test.want {
+				t.Errorf("truncateName(%q, %d) = %q, want %q", test.input, test.maxLength, got, test.want)
+			}
+			if len(got) > test.maxLength {
+				t.Errorf("truncateName(%q, %d) is %d long", test.input, test.maxLength, len(got))
+			}
+		})
+	}
+
+	// the hash keeps names sharing a long prefix apart
+	other := truncateName("fk_"+strings.Repeat("a", 69)+"b", maxIdentifierLength)
+	if other == truncated {
+		t.Errorf("truncateName() gave %q for two different names", other)
+	}
+	if !strings.HasPrefix(truncated, "fk_aaa") {
+		t.Errorf("truncateName() = %q, want the start of the name kept", truncated)
+	}
+}
+
+func TestGetConstraintName(t *testing.T) {
+	tests := []struct {
+		name        string
+		prefix      string
+		tableName   string
+		columnNames []string
+		want        string
+	}{
+		{name: "foreign key", prefix: ForeignKeyPrefix, tableName: "orders", columnNames: []string{"customer_id"}, want: "fk_orders_customer_id"},
+		{name: "composite unique", prefix: UniqueConstraintPrefix, tableName: "items", columnNames: []string{"order_id", "sku"}, want: "uq_items_order_id_sku"},
+		{name: "index", prefix: IndexPrefix, tableName: "t", columnNames: []string{"c"}, want: "idx_t_c"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GetConstraintName(test.prefix, test.tableName, test.columnNames)
+			if got != test.want {
+				t.Errorf("GetConstraintName() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	long := GetConstraintName(ForeignKeyPrefix, strings.Repeat("t", 64), []string{strings.Repeat("c", 64)})
+	if len(long) > maxIdentifierLength || !IsValidIdentifier(long) {
+		t.Errorf("GetConstraintName() = %q, want a valid identifier", long)
+	}
+}
+
+func TestResolveConstraintName(t *testing.T) {
+	tests := []struct {
+		name    string
+		given   string
+		want    string
+		wantErr bool
+	}{
+		{name: "generated when empty", given: "", want: "ck_orders_total"},
+		{name: "given name is kept", given: "positive_total", want: "positive_total"},
+		{name: "invalid given name", given: "bad-name", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ResolveConstraintName(test.given, CheckConstraintPrefix, "orders", []string{"total"})
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ResolveConstraintName() error = %v, want error %v", err, test.wantErr)
+			}
+			if got != test.want {
+				t.Errorf("ResolveConstraintName() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func dependency(tableName, constraintName string, columnNames []string, referenceTableName string, referenceColumnNames []string) *pb.TableDependency {
+	return &pb.TableDependency{
+		TableName: tableName,
+		ForeignKey: &pb.ForeignKey{
+			ConstraintName:       constraintName,
+			ColumnNames:          columnNames,
+			ReferenceTableName:   referenceTableName,
+			ReferenceColumnNames: referenceColumnNames,
+		},
+	}
+}
+
+func TestPlanDropTable(t *testing.T) {
+	// orders <- items.order_id, items <- notes.item_id, orders <- orders.parent_id,
+	// items.order_id <- audits.order_ref (a key on the referencing column)
+	inbound := map[string][]*pb.TableDependency{
+		"orders": {
+			dependency("items", "fk_items_order_id", []string{"order_id"}, "orders", []string{"id"}),
+			dependency("orders", "fk_orders_parent_id", []string{"parent_id"}, "orders", []string{"id"}),
+		},
+		"items": {
+			dependency("audits", "fk_audits_order_ref", []string{"order_ref"}, "items", []string{"order_id"}),
+			dependency("notes", "fk_notes_item_id", []string{"item_id"}, "items", []string{"id"}),
+		},
+	}
+	inboundForeignKeys := func(tableName string) ([]*pb.TableDependency, error) {
+		return inbound[tableName], nil
+	}
+
+	tests := []struct {
+		name        string
+		tableName   string
+		keepColumns bool
+		want        []string
+	}{
+		{
+			name:      "keys on dropped columns go first",
+			tableName: "orders",
+			want:      []string{"audits.fk_audits_order_ref", "items.fk_items_order_id"},
+		},
+		{
+			name:        "kept columns keep their references",
+			tableName:   "orders",
+			keepColumns: true,
+			want:        []string{"items.fk_items_order_id"},
+		},
+		{
+			name:      "unreferenced table",
+			tableName: "notes",
+			want:      nil,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			plan, err := planDropTable(inboundForeignKeys, test.tableName, test.keepColumns)
+			if err != nil {
+				t.Fatalf("planDropTable() error = %v", err)
+			}
+			var got []string
+			for _, dependency := range plan {
+				got = append(got, dependency.TableName+"."+dependency.ForeignKey.ConstraintName)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("planDropTable() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPlanDropTableCycle(t *testing.T) {
+	// a and b reference each other's key columns
+	inbound := map[string][]*pb.TableDependency{
+		"a": {dependency("b", "fk_b_a_id", []string{"a_id"}, "a", []string{"id"})},
+		"b": {dependency("a", "fk_a_b_id", []string{"b_id"}, "b", []string{"a_id"})},
+	}
+	plan, err := planDropTable(func(tableName string) ([]*pb.TableDependency, error) {
+		return inbound[tableName], nil
+	}, "a", false)
+	if err != nil {
+		t.Fatalf("planDropTable() error = %v", err)
+	}
+	if len(plan) != 1 || plan[0].ForeignKey.ConstraintName != "fk_b_a_id" {
+		t.Errorf("planDropTable() = %v, want only fk_b_a_id", plan)
+	}
+}
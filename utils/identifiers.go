@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// the request fields holding identifiers, by name suffix, that end up in SQL
+var identifierFieldSuffixes = []string{
+	"TableName",
+	"TableNames",
+	"ColumnName",
+	"ColumnNames",
+	"ConstraintName",
+	"ConstraintNames",
+	"IndexName",
+	"DatabaseName",
+	"PrimaryKeyColumns",
+	"Charset",
+	"Collation",
+}
+
+// the identifier fields not following the suffixes, keyed by struct name
+var identifierFields = map[string][]string{
+	"Column":         {"Name"},
+	"ColumnPosition": {"After"},
+}
+
+// ValidateIdentifiers checks every table, column, constraint and index name
+// of the request, nested messages included, so no handler puts an
+// unvalidated name into SQL. Empty names are left to the handlers, which know
+// whether they are optional.
+func ValidateIdentifiers(request any) error {
+	return validateIdentifiers(reflect.ValueOf(request))
+}
+
+func validateIdentifiers(v reflect.Value) error {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return validateIdentifiers(v.Elem())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			err := validateIdentifiers(v.Index(i))
+			if err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if isIdentifierField(v.Type().Name(), field.Name) {
+				err := checkIdentifierField(field.Name, v.Field(i))
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			err := validateIdentifiers(v.Field(i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func isIdentifierField(structName, fieldName string) bool {
+	for _, suffix := range identifierFieldSuffixes {
+		if strings.HasSuffix(fieldName, suffix) {
+			return true
+		}
+	}
+	for _, name := range identifierFields[structName] {
+		if fieldName == name {
+			return true
+		}
+	}
+	return false
+}
+
+func checkIdentifierField(fieldName string, v reflect.Value) error {
+	var names []string
+	switch {
+	case v.Kind() == reflect.String:
+		names = []string{v.String()}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for i := 0; i < v.Len(); i++ {
+			names = append(names, v.Index(i).String())
+		}
+	}
+
+	for _, name := range names {
+		if name != "" && !IsValidIdentifier(name) {
+			return fmt.Errorf("invalid %s %q", fieldName, name)
+		}
+	}
+	return nil
+}
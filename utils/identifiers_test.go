@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"testing"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+func TestValidateIdentifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		request any
+		wantErr bool
+	}{
+		{
+			name:    "valid table name",
+			request: &pb.DropTableRequest{TableName: "orders"},
+		},
+		{
+			name:    "invalid table name",
+			request: &pb.DropTableRequest{TableName: "orders; DROP TABLE users"},
+			wantErr: true,
+		},
+		{
+			name:    "empty names are left to the handler",
+			request: &pb.AddForeignKeyRequest{TableName: "orders", ForeignKey: &pb.ForeignKey{}},
+		},
+		{
+			name: "nested column name",
+			request: &pb.CreateTableRequest{
+				TableName: "orders",
+				Columns:   []*pb.Column{{Name: "id"}, {Name: "bad name"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "nested column list",
+			request: &pb.AddForeignKeyRequest{
+				TableName:  "orders",
+				ForeignKey: &pb.ForeignKey{ReferenceTableName: "users", ColumnNames: []string{"user_id", "x`y"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "column position",
+			request: &pb.AddColumnRequest{
+				TableName: "orders",
+				Column:    &pb.Column{Name: "total"},
+				Position:  &pb.ColumnPosition{After: "id)"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "string literals are not identifiers",
+			request: &pb.AddCheckConstraintRequest{
+				TableName:  "orders",
+				Expression: comparison("status", pb.CheckComparisonOperator_EQUAL, stringLiteral("it's open")),
+			},
+		},
+		{
+			name: "column inside a check expression",
+			request: &pb.AddCheckConstraintRequest{
+				TableName:  "orders",
+				Expression: comparison("status'", pb.CheckComparisonOperator_EQUAL, intLiteral(1)),
+			},
+			wantErr: true,
+		},
+		{
+			name:    "comments are not identifiers",
+			request: &pb.UpdateTableCommentRequest{TableName: "orders", Comment: "the orders; all of them"},
+		},
+		{
+			name:    "relationship labels are not identifiers",
+			request: &pb.CreateRelationshipRequest{Name: "order items", SourceTableName: "orders", TargetTableName: "items"},
+		},
+		{
+			name:    "nil request",
+			request: (*pb.DropTableRequest)(nil),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := ValidateIdentifiers(test.request)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ValidateIdentifiers() error = %v, want error %v", err, test.wantErr)
+			}
+		})
+	}
+}
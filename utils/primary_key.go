@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the current time in milliseconds since the epoch, the leading 48 bits of
+// both UUIDv7 and ULID values
+const unixMillisExpression = "FLOOR(UNIX_TIMESTAMP(NOW(3)) * 1000)"
+
+// uuidV7Expression generates a BINARY(16) UUIDv7: the 48 bit timestamp, the
+// version nibble, 12 random bits, the variant nibble and 60 random bits.
+var uuidV7Expression = fmt.Sprintf(
+	"UNHEX(CONCAT(LPAD(HEX(%s), 12, '0'), '7', SUBSTR(HEX(RANDOM_BYTES(2)), 2, 3), HEX(FLOOR(RAND() * 4) + 8), SUBSTR(HEX(RANDOM_BYTES(8)), 2, 15)))",
+	unixMillisExpression,
+)
+
+// ulidExpression generates a CHAR(26) ULID: the 48 bit timestamp in 10
+// characters followed by 80 random bits in 16 characters.
+var ulidExpression = toCrockfordBase32(fmt.Sprintf(
+	"CONCAT(LPAD(CONV(%s, 10, 32), 10, '0'), LPAD(CONV(HEX(RANDOM_BYTES(5)), 16, 32), 8, '0'), LPAD(CONV(HEX(RANDOM_BYTES(5)), 16, 32), 8, '0'))",
+	unixMillisExpression,
+))
+
+// toCrockfordBase32 maps the 0-9A-V digits produced by CONV to the Crockford
+// alphabet used by ULIDs. The letters are replaced from the last one down, so
+// a replaced letter is never replaced again.
+func toCrockfordBase32(expression string) string {
+	const convDigits = "IJKLMNOPQRSTUV"
+	const crockfordDigits = "JKMNPQRSTVWXYZ"
+	for i := len(convDigits) - 1; i >= 0; i-- {
+		expression = fmt.Sprintf("REPLACE(%s, '%c', '%c')", expression, convDigits[i], crockfordDigits[i])
+	}
+	return expression
+}
+
+// GetPrimaryKeyColumnType returns the column type of the generated id column,
+// as reported by INFORMATION_SCHEMA.
+func GetPrimaryKeyColumnType(primaryKeyType pb.PrimaryKeyType) string {
+	switch primaryKeyType {
+	case pb.PrimaryKeyType_UUID:
+		return "binary(16)"
+	case pb.PrimaryKeyType_ULID:
+		return "char(26)"
+	default:
+		return "bigint unsigned"
+	}
+}
+
+// GetPrimaryKeyDefault returns how the generated id column gets its values
+// when the client does not provide them.
+func GetPrimaryKeyDefault(primaryKeyType pb.PrimaryKeyType) string {
+	switch primaryKeyType {
+	case pb.PrimaryKeyType_UUID:
+		return fmt.Sprintf("NOT NULL DEFAULT (%s)", uuidV7Expression)
+	case pb.PrimaryKeyType_ULID:
+		return fmt.Sprintf("NOT NULL DEFAULT (%s)", ulidExpression)
+	default:
+		return "AUTO_INCREMENT"
+	}
+}
+
+// ValidatePrimaryKeyColumns checks that a composite primary key names each of
+// the declared columns at most once.
+func ValidatePrimaryKeyColumns(primaryKeyColumns []string, columns []*pb.Column) error {
+	if len(primaryKeyColumns) == 0 {
+		return fmt.Errorf("composite primary key requires at least one column")
+	}
+
+	seen := make(map[string]bool)
+	for _, primaryKeyColumn := range primaryKeyColumns {
+		if seen[strings.ToLower(primaryKeyColumn)] {
+			return fmt.Errorf("column %s appears twice in the primary key", primaryKeyColumn)
+		}
+		seen[strings.ToLower(primaryKeyColumn)] = true
+
+		declared := false
+		for _, column := range columns {
+			if strings.EqualFold(column.Name, primaryKeyColumn) {
+				declared = true
+				break
+			}
+		}
+		if !declared {
+			return fmt.Errorf("primary key column %s is not declared", primaryKeyColumn)
+		}
+	}
+
+	return nil
+}
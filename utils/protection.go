@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"database/sql"
+	"strings"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the columns create_table.tmpl adds for the generated primary key and for the
+// audit columns
+const idColumnName = "id"
+
+var auditColumnNames = map[pb.AuditColumn]string{
+	pb.AuditColumn_CREATOR_ID: "creator_id",
+	pb.AuditColumn_CREATED_AT: "created_at",
+	pb.AuditColumn_UPDATED_AT: "updated_at",
+}
+
+// the internal bookkeeping tables, on top of the trash tables
+var systemTableNames = []string{TrashTableName, RelationshipsTableName, ColumnMetadataTableName, TenantsTableName, TenantDropsTableName}
+
+// SystemColumns holds the columns of a table managed by the platform, which
+// must not be dropped, shadowed or re-keyed by users.
+type SystemColumns map[string]bool
+
+// Contains reports whether the column is a system column of the table.
+func (c SystemColumns) Contains(columnName string) bool {
+	return c[strings.ToLower(columnName)]
+}
+
+// NewTableSystemColumns returns the system columns create_table.tmpl adds to a
+// new table with the primary key type and audit columns.
+func NewTableSystemColumns(primaryKeyType pb.PrimaryKeyType, auditColumns map[pb.AuditColumn]bool) SystemColumns {
+	systemColumns := make(SystemColumns)
+	if primaryKeyType != pb.PrimaryKeyType_COMPOSITE {
+		systemColumns[idColumnName] = true
+	}
+	for auditColumn, enabled := range auditColumns {
+		if enabled {
+			systemColumns[auditColumnNames[auditColumn]] = true
+		}
+	}
+	return systemColumns
+}
+
+// GetSystemColumns returns the system columns of an existing table: the id
+// column when it is the generated primary key, and the configured audit
+// columns the table was created with. A user column that merely shares the
+// name of an audit column, in a table that opted out of it, is not one.
+func (c *SystemConfig) GetSystemColumns(db *sql.DB, databaseName, tableName string) (SystemColumns, error) {
+	systemColumns := make(SystemColumns)
+
+	primaryKeyColumns, err := GetPrimaryKeyColumns(db, databaseName, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(primaryKeyColumns) == 1 && strings.EqualFold(primaryKeyColumns[0], idColumnName) {
+		systemColumns[idColumnName] = true
+	}
+
+	for _, auditColumn := range c.AuditColumns {
+		var managed bool
+		switch auditColumn {
+		case pb.AuditColumn_CREATOR_ID:
+			managed, err = c.isCreatorColumn(db, databaseName, tableName)
+		case pb.AuditColumn_CREATED_AT:
+			managed, err = isTimestampColumn(db, databaseName, tableName, auditColumnNames[auditColumn], false)
+		case pb.AuditColumn_UPDATED_AT:
+			managed, err = isTimestampColumn(db, databaseName, tableName, auditColumnNames[auditColumn], true)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if managed {
+			systemColumns[auditColumnNames[auditColumn]] = true
+		}
+	}
+
+	return systemColumns, nil
+}
+
+// isCreatorColumn reports whether the table has a creator_id column referencing
+// the users table, as create_table.tmpl adds it.
+func (c *SystemConfig) isCreatorColumn(db *sql.DB, databaseName, tableName string) (bool, error) {
+	query := "SELECT COUNT(*) FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ? AND REFERENCED_TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME = ?"
+	var count int
+	err := db.QueryRow(query, databaseName, tableName, auditColumnNames[pb.AuditColumn_CREATOR_ID], c.IdentitySchema, c.UsersTable).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// isTimestampColumn reports whether the column is a TIMESTAMP defaulting to the
+// current time, and updated with it when onUpdate is set, as create_table.tmpl
+// adds created_at and updated_at.
+func isTimestampColumn(db *sql.DB, databaseName, tableName, columnName string, onUpdate bool) (bool, error) {
+	query := "SELECT DATA_TYPE, COLUMN_DEFAULT, EXTRA FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND COLUMN_NAME = ?"
+	var dataType, extra string
+	var columnDefault sql.NullString
+	err := db.QueryRow(query, databaseName, tableName, columnName).Scan(&dataType, &columnDefault, &extra)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	// MariaDB reports the default as current_timestamp()
+	if !strings.EqualFold(dataType, "timestamp") || !strings.HasPrefix(strings.ToLower(columnDefault.String), "current_timestamp") {
+		return false, nil
+	}
+	return !onUpdate || strings.Contains(strings.ToLower(extra), "on update"), nil
+}
+
+// IsSystemTable reports whether the table is an internal bookkeeping table or
+// a trash table. Other tables starting with an underscore belong to users.
+func IsSystemTable(tableName string) bool {
+	for _, systemTableName := range systemTableNames {
+		if strings.EqualFold(tableName, systemTableName) {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.ToLower(tableName), trashTablePrefix)
+}
+
+// SystemTableNames returns the internal bookkeeping tables, for the queries
+// filtering them out along with the trash tables.
+func SystemTableNames() []string {
+	return systemTableNames
+}
+
+// TrashTablePattern returns the LIKE pattern matching the trash tables.
+func TrashTablePattern() string {
+	return strings.ReplaceAll(trashTablePrefix, "_", `\_`) + "%"
+}
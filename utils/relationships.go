@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the bookkeeping table recording the relationships created through the
+// service, so they can be listed as a logical model
+const RelationshipsTableName = "_relationships"
+
+const relationshipColumns = "id, name, kind, source_table_name, target_table_name, column_names, target_column_names, junction_table_name, constraint_names, created_at"
+
+func CreateRelationshipsTable(db *sql.DB) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  name VARCHAR(64) NOT NULL UNIQUE,
+  kind VARCHAR(16) NOT NULL,
+  source_table_name VARCHAR(64) NOT NULL,
+  target_table_name VARCHAR(64) NOT NULL,
+  column_names TEXT NOT NULL,
+  target_column_names TEXT,
+  junction_table_name VARCHAR(64) NOT NULL DEFAULT '',
+  constraint_names TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, RelationshipsTableName)
+	_, err := db.Exec(query)
+	return err
+}
+
+// AddRelationship records the relationship and sets its id.
+func AddRelationship(db *sql.DB, relationship *pb.Relationship) error {
+	query := fmt.Sprintf(
+		"INSERT INTO %s (name, kind, source_table_name, target_table_name, column_names, target_column_names, junction_table_name, constraint_names) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		RelationshipsTableName,
+	)
+	result, err := db.Exec(
+		query,
+		relationship.Name,
+		relationship.Type.String(),
+		relationship.SourceTableName,
+		relationship.TargetTableName,
+		strings.Join(relationship.ColumnNames, ","),
+		strings.Join(relationship.TargetColumnNames, ","),
+		relationship.JunctionTableName,
+		strings.Join(relationship.ConstraintNames, ","),
+	)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	relationship.Id = uint64(id)
+	return nil
+}
+
+func CheckRelationshipExists(db *sql.DB, name string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("SELECT id FROM %s WHERE name = ?", RelationshipsTableName), name)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	return rows.Next(), nil
+}
+
+// GetRelationships returns the relationships involving the table, or all of
+// them when the table name is empty.
+func GetRelationships(db *sql.DB, tableName string) ([]*pb.Relationship, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s", relationshipColumns, RelationshipsTableName)
+	var args []any
+	if tableName != "" {
+		query += " WHERE source_table_name = ? OR target_table_name = ? OR junction_table_name = ?"
+		args = append(args, tableName, tableName, tableName)
+	}
+	query += " ORDER BY id"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var relationships []*pb.Relationship
+	for rows.Next() {
+		var kind, columnNames, constraintNames string
+		var targetColumnNames sql.NullString
+		relationship := &pb.Relationship{}
+		err = rows.Scan(
+			&relationship.Id,
+			&relationship.Name,
+			&kind,
+			&relationship.SourceTableName,
+			&relationship.TargetTableName,
+			&columnNames,
+			&targetColumnNames,
+			&relationship.JunctionTableName,
+			&constraintNames,
+			&relationship.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		relationship.Type = pb.RelationshipType(pb.RelationshipType_value[kind])
+		relationship.ColumnNames = strings.Split(columnNames, ",")
+		relationship.ConstraintNames = strings.Split(constraintNames, ",")
+		if targetColumnNames.String != "" {
+			relationship.TargetColumnNames = strings.Split(targetColumnNames.String, ",")
+		}
+		relationships = append(relationships, relationship)
+	}
+
+	return relationships, rows.Err()
+}
+
+// DeleteTableRelationships forgets the relationships involving a dropped table.
+func DeleteTableRelationships(db *sql.DB, tableName string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE source_table_name = ? OR target_table_name = ? OR junction_table_name = ?",
+		RelationshipsTableName,
+	)
+	_, err := db.Exec(query, tableName, tableName, tableName)
+	return err
+}
+
+// DeleteForeignKeyRelationships forgets the relationships held by a dropped
+// foreign key constraint of the table.
+func DeleteForeignKeyRelationships(db *sql.DB, tableName string, constraintName string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE (target_table_name = ? OR junction_table_name = ?) AND FIND_IN_SET(?, constraint_names) > 0",
+		RelationshipsTableName,
+	)
+	_, err := db.Exec(query, tableName, tableName, constraintName)
+	return err
+}
+
+// DeleteColumnRelationships forgets the relationships whose columns include a
+// dropped column of the table.
+func DeleteColumnRelationships(db *sql.DB, tableName string, columnName string) error {
+	query := fmt.Sprintf(
+		"DELETE FROM %s WHERE (junction_table_name = '' AND target_table_name = ? AND FIND_IN_SET(?, column_names) > 0) OR (junction_table_name = ? AND (FIND_IN_SET(?, column_names) > 0 OR FIND_IN_SET(?, target_column_names) > 0))",
+		RelationshipsTableName,
+	)
+	_, err := db.Exec(query, tableName, columnName, tableName, columnName, columnName)
+	return err
+}
+
+// RenameRelationshipColumn renames a column of the table in the relationships
+// holding it, on the referencing side or in the junction table.
+func RenameRelationshipColumn(db *sql.DB, tableName, columnName, newColumnName string) error {
+	relationships, err := GetRelationships(db, tableName)
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET column_names = ?, target_column_names = ? WHERE id = ?", RelationshipsTableName)
+	for _, relationship := range relationships {
+		// the columns live in the junction table, or in the target table
+		holder := relationship.TargetTableName
+		if relationship.JunctionTableName != "" {
+			holder = relationship.JunctionTableName
+		}
+		if !strings.EqualFold(holder, tableName) {
+			continue
+		}
+
+		columnNames := renameColumn(relationship.ColumnNames, columnName, newColumnName)
+		targetColumnNames := renameColumn(relationship.TargetColumnNames, columnName, newColumnName)
+		_, err = db.Exec(query, strings.Join(columnNames, ","), strings.Join(targetColumnNames, ","), relationship.Id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameColumn(columnNames []string, columnName, newColumnName string) []string {
+	renamed := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		renamed[i] = name
+		if strings.EqualFold(name, columnName) {
+			renamed[i] = newColumnName
+		}
+	}
+	return renamed
+}
+
+// GetRelationshipColumnNames returns the requested column names, or names of
+// the form <table>_<column> for each referenced key column.
+func GetRelationshipColumnNames(columnNames []string, tableName string, referenceColumnNames []string) ([]string, error) {
+	if len(columnNames) == 0 {
+		columnNames = make([]string, len(referenceColumnNames))
+		for i, referenceColumnName := range referenceColumnNames {
+			columnNames[i] = fmt.Sprintf("%s_%s", tableName, referenceColumnName)
+		}
+	}
+	if len(columnNames) != len(referenceColumnNames) {
+		return nil, fmt.Errorf("relationship must have as many columns as the key of %s", tableName)
+	}
+
+	for _, columnName := range columnNames {
+		if !IsValidIdentifier(columnName) {
+			return nil, fmt.Errorf("invalid column name %q", columnName)
+		}
+	}
+
+	return columnNames, nil
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/isaacwassouf/schema-service/config"
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the column referenced by foreign keys to the users table
+const UsersIdColumnName = "id"
+
+// SystemConfig describes the system columns added to every table and the
+// users table they point at, as configured for the deployment.
+type SystemConfig struct {
+	// the schema holding the users table
+	IdentitySchema string
+	// the users table referenced by creator_id and user foreign keys
+	UsersTable string
+	// the primary key type of new tables
+	PrimaryKeyType pb.PrimaryKeyType
+	// the primary key type of the users table
+	UsersPrimaryKeyType pb.PrimaryKeyType
+	// the audit columns added to new tables unless a request opts out
+	AuditColumns []pb.AuditColumn
+}
+
+// NewSystemConfig validates the configured system columns and users table.
+func NewSystemConfig(systemColumns *config.SystemColumnsConfig) (*SystemConfig, error) {
+	systemConfig := &SystemConfig{
+		IdentitySchema: systemColumns.IdentitySchema,
+		UsersTable:     systemColumns.UsersTable,
+	}
+	if systemConfig.IdentitySchema == "" || strings.Contains(systemConfig.IdentitySchema, "`") {
+		return nil, fmt.Errorf("invalid identity schema %q", systemConfig.IdentitySchema)
+	}
+	if !IsValidIdentifier(systemConfig.UsersTable) {
+		return nil, fmt.Errorf("invalid users table %q", systemConfig.UsersTable)
+	}
+
+	var err error
+	systemConfig.PrimaryKeyType, err = parsePrimaryKeyType(systemColumns.PrimaryKeyType)
+	if err != nil {
+		return nil, err
+	}
+	systemConfig.UsersPrimaryKeyType, err = parsePrimaryKeyType(systemColumns.UsersPrimaryKeyType)
+	if err != nil {
+		return nil, fmt.Errorf("users table: %w", err)
+	}
+
+	// an empty list disables the audit columns
+	for _, auditColumn := range systemColumns.AuditColumns {
+		value, ok := pb.AuditColumn_value[strings.ToUpper(strings.TrimSpace(auditColumn))]
+		if !ok {
+			return nil, fmt.Errorf("invalid audit column %q", auditColumn)
+		}
+		systemConfig.AuditColumns = append(systemConfig.AuditColumns, pb.AuditColumn(value))
+	}
+
+	return systemConfig, nil
+}
+
+// parsePrimaryKeyType parses a configured primary key type. Composite keys are
+// named per table, they cannot be configured.
+func parsePrimaryKeyType(primaryKeyType string) (pb.PrimaryKeyType, error) {
+	primaryKeyType = strings.ToUpper(primaryKeyType)
+	value, ok := pb.PrimaryKeyType_value[primaryKeyType]
+	if !ok || pb.PrimaryKeyType(value) == pb.PrimaryKeyType_DEFAULT || pb.PrimaryKeyType(value) == pb.PrimaryKeyType_COMPOSITE {
+		return 0, fmt.Errorf("invalid primary key type %q", primaryKeyType)
+	}
+	return pb.PrimaryKeyType(value), nil
+}
+
+// IsUsersTable reports whether foreign keys to the table point at the users
+// table in the identity schema.
+func (c *SystemConfig) IsUsersTable(tableName string) bool {
+	return tableName == c.UsersTable
+}
+
+// UsersTableReference returns the qualified users table name to use in DDL.
+func (c *SystemConfig) UsersTableReference() string {
+	return fmt.Sprintf("`%s`.`%s`", c.IdentitySchema, c.UsersTable)
+}
+
+// UsersIdColumnType returns the column type of the users id, as reported by
+// INFORMATION_SCHEMA.
+func (c *SystemConfig) UsersIdColumnType() string {
+	return GetPrimaryKeyColumnType(c.UsersPrimaryKeyType)
+}
+
+// ResolvePrimaryKeyType returns the requested primary key type, or the
+// deployment one when the request leaves it to the default.
+func (c *SystemConfig) ResolvePrimaryKeyType(primaryKeyType pb.PrimaryKeyType) pb.PrimaryKeyType {
+	if primaryKeyType == pb.PrimaryKeyType_DEFAULT {
+		return c.PrimaryKeyType
+	}
+	return primaryKeyType
+}
+
+// ResolveAuditColumns returns the configured audit columns minus the ones the
+// request opts out of.
+func (c *SystemConfig) ResolveAuditColumns(excludedAuditColumns []pb.AuditColumn) map[pb.AuditColumn]bool {
+	auditColumns := make(map[pb.AuditColumn]bool)
+	for _, auditColumn := range c.AuditColumns {
+		auditColumns[auditColumn] = true
+	}
+	for _, auditColumn := range excludedAuditColumns {
+		delete(auditColumns, auditColumn)
+	}
+	return auditColumns
+}
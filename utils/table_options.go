@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the storage engines tables can be created with
+var tableEngines = []string{"InnoDB", "MyISAM", "MEMORY"}
+
+// the row formats accepted by ROW_FORMAT
+var tableRowFormats = []string{"DEFAULT", "DYNAMIC", "COMPACT", "REDUNDANT", "COMPRESSED", "FIXED"}
+
+// ValidateTableOptions normalizes the table options in place and checks them
+// against the values the server supports, so they are safe to interpolate into
+// the DDL templates.
+func ValidateTableOptions(db *sql.DB, options *pb.TableOptions) error {
+	if options == nil {
+		return nil
+	}
+
+	if options.Engine != "" {
+		engine, ok := findOption(tableEngines, options.Engine)
+		if !ok {
+			return fmt.Errorf("unsupported engine %q", options.Engine)
+		}
+		options.Engine = engine
+	}
+
+	if options.RowFormat != "" {
+		rowFormat, ok := findOption(tableRowFormats, options.RowFormat)
+		if !ok {
+			return fmt.Errorf("unsupported row format %q", options.RowFormat)
+		}
+		options.RowFormat = rowFormat
+	}
+
+	if options.Charset != "" || options.Collation != "" {
+		charset, collation, err := ResolveCharsetAndCollation(db, options.Charset, options.Collation)
+		if err != nil {
+			return err
+		}
+		options.Charset = charset
+		options.Collation = collation
+	}
+
+	return nil
+}
+
+func findOption(values []string, value string) (string, bool) {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// ResolveCharsetAndCollation checks that the collation exists and belongs to
+// the charset. Either one may be empty, in which case it is derived from the
+// other.
+func ResolveCharsetAndCollation(db *sql.DB, charset, collation string) (string, string, error) {
+	if charset != "" && !IsValidIdentifier(charset) {
+		return "", "", fmt.Errorf("invalid charset %q", charset)
+	}
+	if collation != "" && !IsValidIdentifier(collation) {
+		return "", "", fmt.Errorf("invalid collation %q", collation)
+	}
+
+	query := "SELECT CHARACTER_SET_NAME, COLLATION_NAME FROM INFORMATION_SCHEMA.COLLATIONS WHERE "
+	var args []any
+	if collation != "" {
+		query += "COLLATION_NAME = ?"
+		args = append(args, collation)
+	} else {
+		query += "CHARACTER_SET_NAME = ? AND IS_DEFAULT = 'Yes'"
+		args = append(args, charset)
+	}
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return "", "", err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if collation != "" {
+			return "", "", fmt.Errorf("unknown collation %q", collation)
+		}
+		return "", "", fmt.Errorf("unknown charset %q", charset)
+	}
+
+	var resolvedCharset, resolvedCollation string
+	err = rows.Scan(&resolvedCharset, &resolvedCollation)
+	if err != nil {
+		return "", "", err
+	}
+	if charset != "" && !strings.EqualFold(charset, resolvedCharset) {
+		return "", "", fmt.Errorf("collation %s does not belong to charset %s", resolvedCollation, charset)
+	}
+
+	return resolvedCharset, resolvedCollation, nil
+}
+
+// ValidateColumnAttributes checks the charset, collation and comment of the
+// column and returns the resolved charset and collation. Only the string
+// column types carry a charset.
+func ValidateColumnAttributes(db *sql.DB, column *pb.Column, columnType string) (string, string, error) {
+	err := ValidateColumnComment(column.Comment)
+	if err != nil {
+		return "", "", err
+	}
+
+	if column.Charset == "" && column.Collation == "" {
+		return "", "", nil
+	}
+	if !strings.HasPrefix(columnType, "VARCHAR") && columnType != "TEXT" {
+		return "", "", fmt.Errorf("column %s of type %s cannot have a charset or collation", column.Name, columnType)
+	}
+
+	return ResolveCharsetAndCollation(db, column.Charset, column.Collation)
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+
+	db "github.com/isaacwassouf/schema-service/database"
+)
+
+// the bookkeeping table, in the default database, mapping projects to the
+// databases they are routed to
+const TenantsTableName = "_tenants"
+
+// the bookkeeping table, in the default database, holding the project
+// databases whose drop has started but not finished
+const TenantDropsTableName = "_tenant_drops"
+
+// the gRPC metadata key selecting the project database of a request
+const ProjectIdMetadataKey = "x-project-id"
+
+var projectIdRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+func IsValidProjectId(projectId string) bool {
+	return projectIdRegex.MatchString(projectId)
+}
+
+func CreateTenantsTable(db *sql.DB) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  project_id VARCHAR(64) PRIMARY KEY,
+  database_name VARCHAR(64) NOT NULL UNIQUE,
+  username VARCHAR(32) NOT NULL DEFAULT '',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, TenantsTableName)
+	_, err := db.Exec(query)
+	if err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  project_id VARCHAR(64) PRIMARY KEY,
+  database_name VARCHAR(64) NOT NULL,
+  username VARCHAR(32) NOT NULL DEFAULT '',
+  started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, TenantDropsTableName)
+	_, err = db.Exec(query)
+	return err
+}
+
+// GetTenantDatabaseName returns the database registered for the project, or
+// ErrTenantNotFound.
+func GetTenantDatabaseName(controlDB *sql.DB, projectId string) (string, error) {
+	var databaseName string
+	err := controlDB.QueryRow(
+		fmt.Sprintf("SELECT database_name FROM %s WHERE project_id = ?", TenantsTableName),
+		projectId,
+	).Scan(&databaseName)
+	if err == sql.ErrNoRows {
+		return "", db.ErrTenantNotFound
+	}
+	return databaseName, err
+}
+
+// GetTenantProjectIds returns the projects registered for routing.
+func GetTenantProjectIds(controlDB *sql.DB) ([]string, error) {
+	rows, err := controlDB.Query(fmt.Sprintf("SELECT project_id FROM %s ORDER BY project_id", TenantsTableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var projectIds []string
+	for rows.Next() {
+		var projectId string
+		err = rows.Scan(&projectId)
+		if err != nil {
+			return nil, err
+		}
+		projectIds = append(projectIds, projectId)
+	}
+
+	return projectIds, rows.Err()
+}
+
+// CreateBookkeepingTables creates the internal tables the service keeps in
+// every managed database.
+func CreateBookkeepingTables(db *sql.DB) error {
+	err := CreateTrashTable(db)
+	if err != nil {
+		return fmt.Errorf("failed to create the trash table: %w", err)
+	}
+
+	err = CreateRelationshipsTable(db)
+	if err != nil {
+		return fmt.Errorf("failed to create the relationships table: %w", err)
+	}
+
+	err = CreateColumnMetadataTable(db)
+	if err != nil {
+		return fmt.Errorf("failed to create the column metadata table: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// TLSReloader serves the certificate and client CA bundle of the listener
+// from files, reloading them when they change so certificates can be rotated
+// without restarting the server.
+type TLSReloader struct {
+	certFile string
+	keyFile  string
+	// the CA bundle client certificates are verified against, empty to
+	// accept clients without one
+	clientCAFile string
+
+	certificate atomic.Pointer[tls.Certificate]
+	clientCAs   atomic.Pointer[x509.CertPool]
+	modTimes    map[string]time.Time
+}
+
+func NewTLSReloader(certFile, keyFile, clientCAFile string) (*TLSReloader, error) {
+	reloader := &TLSReloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: clientCAFile,
+		modTimes:     make(map[string]time.Time),
+	}
+
+	_, err := reloader.reload()
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// TLSConfig returns the server configuration, requiring a verified client
+// certificate when a client CA bundle is configured.
+func (r *TLSReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the client CAs can change, each handshake gets the current ones
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{
+				MinVersion: tls.VersionTLS12,
+				// the returned config replaces the one gRPC adds h2 to
+				NextProtos: []string{"h2"},
+				GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+					return r.certificate.Load(), nil
+				},
+			}
+			if r.clientCAFile != "" {
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+				config.ClientCAs = r.clientCAs.Load()
+			}
+			return config, nil
+		},
+	}
+}
+
+// Watch polls the files and reloads them when any of them changes. Invalid
+// files are logged and the current ones kept. It returns once the context is
+// done.
+func (r *TLSReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := r.reload()
+		if err != nil {
+			log.Printf("kept the current TLS certificates: %v", err)
+			continue
+		}
+		if reloaded {
+			log.Printf("reloaded the TLS certificates")
+		}
+	}
+}
+
+// reload loads the files if they changed since the last load and reports
+// whether they did.
+func (r *TLSReloader) reload() (bool, error) {
+	files := []string{r.certFile, r.keyFile}
+	if r.clientCAFile != "" {
+		files = append(files, r.clientCAFile)
+	}
+
+	modTimes := make(map[string]time.Time)
+	changed := false
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, err
+		}
+		modTimes[file] = info.ModTime()
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			changed = true
+		}
+	}
+	if !changed {
+		return false, nil
+	}
+
+	certificate, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, err
+	}
+
+	var clientCAs *x509.CertPool
+	if r.clientCAFile != "" {
+		bundle, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return false, err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(bundle) {
+			return false, fmt.Errorf("no certificate found in %s", r.clientCAFile)
+		}
+	}
+
+	r.certificate.Store(&certificate)
+	r.clientCAs.Store(clientCAs)
+	r.modTimes = modTimes
+	return true, nil
+}
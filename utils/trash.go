@@ -0,0 +1,174 @@
+package utils
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	pb "github.com/isaacwassouf/schema-service/protobufs/schema_management_service"
+)
+
+// the bookkeeping table recording what has been moved to the trash
+const TrashTableName = "_trash"
+
+// the prefix of the tables holding trashed tables and column data
+const trashTablePrefix = "_trash_"
+
+func CreateTrashTable(db *sql.DB) error {
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+  id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+  kind VARCHAR(16) NOT NULL,
+  table_name VARCHAR(64) NOT NULL,
+  column_name VARCHAR(64) NOT NULL DEFAULT '',
+  column_definition TEXT,
+  key_columns TEXT,
+  restore_sql TEXT,
+  trash_table_name VARCHAR(64) NOT NULL UNIQUE,
+  dropped_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`, TrashTableName)
+	_, err := db.Exec(query)
+	return err
+}
+
+// GetTrashTableName returns the reserved _trash_<ts>_<name> table name used to
+// hold a trashed table or the data of a trashed column. The timestamp goes
+// down to the nanosecond, so trashing a table again right after restoring it
+// does not collide with the previous entry.
+func GetTrashTableName(droppedAt time.Time, parts ...string) string {
+	droppedAt = droppedAt.UTC()
+	timestamp := fmt.Sprintf("%s%09d", droppedAt.Format("20060102150405"), droppedAt.Nanosecond())
+	name := fmt.Sprintf("%s%s_%s", trashTablePrefix, timestamp, strings.Join(parts, "_"))
+	return truncateIdentifier(name)
+}
+
+// AddTrashEntry records a trash entry and returns its id. It is recorded
+// before the table or column is moved, so nothing ever sits in a trash table
+// the trash does not know about. The restore statements run once the table is
+// back, e.g. to add back the foreign keys it gave up.
+func AddTrashEntry(db *sql.DB, entry *pb.TrashEntry, columnDefinition string, keyColumns []string, restoreSQL []string) (uint64, error) {
+	var restoreSQLJSON sql.NullString
+	if len(restoreSQL) > 0 {
+		content, err := json.Marshal(restoreSQL)
+		if err != nil {
+			return 0, err
+		}
+		restoreSQLJSON = sql.NullString{String: string(content), Valid: true}
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (kind, table_name, column_name, column_definition, key_columns, restore_sql, trash_table_name) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		TrashTableName,
+	)
+	result, err := db.Exec(
+		query,
+		entry.Kind.String(),
+		entry.TableName,
+		entry.ColumnName,
+		columnDefinition,
+		strings.Join(keyColumns, ","),
+		restoreSQLJSON,
+		entry.TrashTableName,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return uint64(id), nil
+}
+
+// TrashEntryDetails holds what is needed to restore a trashed table or column
+// on top of the entry reported to clients.
+type TrashEntryDetails struct {
+	Entry            *pb.TrashEntry
+	ColumnDefinition string
+	KeyColumns       []string
+	RestoreSQL       []string
+}
+
+func GetTrashEntries(db *sql.DB) ([]*TrashEntryDetails, error) {
+	query := fmt.Sprintf(
+		"SELECT id, kind, table_name, column_name, column_definition, key_columns, restore_sql, trash_table_name, dropped_at FROM %s ORDER BY id",
+		TrashTableName,
+	)
+	return queryTrashEntries(db, query)
+}
+
+// GetTrashEntry returns the trash entry with the id, or nil when there is none.
+func GetTrashEntry(db *sql.DB, id uint64) (*TrashEntryDetails, error) {
+	query := fmt.Sprintf(
+		"SELECT id, kind, table_name, column_name, column_definition, key_columns, restore_sql, trash_table_name, dropped_at FROM %s WHERE id = ?",
+		TrashTableName,
+	)
+	entries, err := queryTrashEntries(db, query, id)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// GetExpiredTrashEntries returns the trash entries older than the retention.
+func GetExpiredTrashEntries(db *sql.DB, retention time.Duration) ([]*TrashEntryDetails, error) {
+	query := fmt.Sprintf(
+		"SELECT id, kind, table_name, column_name, column_definition, key_columns, restore_sql, trash_table_name, dropped_at FROM %s WHERE dropped_at < NOW() - INTERVAL ? SECOND ORDER BY id",
+		TrashTableName,
+	)
+	return queryTrashEntries(db, query, int64(retention.Seconds()))
+}
+
+func DeleteTrashEntry(db *sql.DB, id uint64) error {
+	_, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", TrashTableName), id)
+	return err
+}
+
+func queryTrashEntries(db *sql.DB, query string, args ...any) ([]*TrashEntryDetails, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*TrashEntryDetails
+	for rows.Next() {
+		var kind string
+		var columnDefinition, keyColumns, restoreSQL sql.NullString
+		entry := &pb.TrashEntry{}
+		err = rows.Scan(
+			&entry.Id,
+			&kind,
+			&entry.TableName,
+			&entry.ColumnName,
+			&columnDefinition,
+			&keyColumns,
+			&restoreSQL,
+			&entry.TrashTableName,
+			&entry.DroppedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		entry.Kind = pb.TrashEntryKind(pb.TrashEntryKind_value[kind])
+
+		details := &TrashEntryDetails{
+			Entry:            entry,
+			ColumnDefinition: columnDefinition.String,
+		}
+		if keyColumns.String != "" {
+			details.KeyColumns = strings.Split(keyColumns.String, ",")
+		}
+		if restoreSQL.String != "" {
+			err = json.Unmarshal([]byte(restoreSQL.String), &details.RestoreSQL)
+			if err != nil {
+				return nil, fmt.Errorf("invalid restore statements of trash entry %d: %w", entry.Id, err)
+			}
+		}
+		entries = append(entries, details)
+	}
+
+	return entries, rows.Err()
+}
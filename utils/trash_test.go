@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetTrashTableName(t *testing.T) {
+	droppedAt := time.Date(2026, 10, 18, 12, 30, 45, 123456789, time.UTC)
+
+	tests := []struct {
+		name      string
+		droppedAt time.Time
+		parts     []string
+		want      string
+	}{
+		{
+			name:      "table",
+			droppedAt: droppedAt,
+			parts:     []string{"orders"},
+			want:      "_trash_20261018123045123456789_orders",
+		},
+		{
+			name:      "column",
+			droppedAt: droppedAt,
+			parts:     []string{"orders", "total"},
+			want:      "_trash_20261018123045123456789_orders_total",
+		},
+		{
+			name:      "local time is stored as UTC",
+			droppedAt: droppedAt.In(time.FixedZone("UTC+2", 2*60*60)),
+			parts:     []string{"orders"},
+			want:      "_trash_20261018123045123456789_orders",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := GetTrashTableName(test.droppedAt, test.parts...)
+			if got != test.want {
+				t.Errorf("GetTrashTableName() = %q, want %q", got, test.want)
+			}
+			if !IsSystemTable(got) {
+				t.Errorf("GetTrashTableName() = %q is not a system table", got)
+			}
+		})
+	}
+
+	// the same table trashed twice within a second gets two names
+	first := GetTrashTableName(droppedAt, "orders")
+	second := GetTrashTableName(droppedAt.Add(time.Millisecond), "orders")
+	if first == second {
+		t.Errorf("GetTrashTableName() gave %q twice", first)
+	}
+
+	long := GetTrashTableName(droppedAt, strings.Repeat("t", 64), strings.Repeat("c", 64))
+	if len(long) > maxIdentifierLength || !IsValidIdentifier(long) || !IsSystemTable(long) {
+		t.Errorf("GetTrashTableName() = %q, want a valid trash table name", long)
+	}
+}